@@ -3,8 +3,11 @@ package command
 import (
 	"fmt"
 	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/ports"
+	"github.com/hpehl/whatunga/template"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -34,16 +37,27 @@ To add certain objects, you need to change the context first: To add servers
 you need to change the context to a host; to add deployments you need to change
 the context to a server group.
 
+New objects are created using default values: Server groups use the first
+profile and socket binding group of the domain template. Servers are assigned
+to the first server group and the port offset is incremented by 50 for each
+server. The name of a deployment is derived from its file name with points
+replaced by dashes.
+
 When adding multiple values, you can use a pattern to create unique names:
 
     - %w: Resolves to the project name
     - %v: Resolves to the project version
     - %h: Inserts the current host name (applicable when adding servers
       to a host)
-    - %g: Inserts the current server group name (applicable when adding
-      deployments to a server group)
-    - %[n]c: A counter which starts at zero and which is incremented for
-      each added object.
+    - %g: Inserts the current server group name (applicable if the
+      current context is a server group)
+    - %[n]c: A counter which starts at n (zero if omitted) and which is
+      incremented for each added object. Use a leading zero to pad the
+      counter: %01c resolves to 01, 02, ..., 10, 11.
+    - %%: A literal percent sign
+
+Patterns are expanded in names only: The password of a user and the path of a
+deployment are taken as is.
 
 Example:
 
@@ -60,7 +74,7 @@ servers are added to the current project model:
     foo-master-server-5
 
 It's up to the user to choose a pattern which generates unique names.
-Non-unique names will lead to an error.`,
+Non-unique names will lead to an error and none of the objects is added.`,
 	// tab completer
 	func(_ *model.Project, query, cmdline string) ([]string, int) {
 		var matches []string
@@ -127,7 +141,7 @@ matches: %v
 		}
 	},
 	// action
-	func(_ *model.Project, args []string) error {
+	func(project *model.Project, args []string) error {
 		if len(args) == 0 {
 			return fmt.Errorf("Missing arguments. Usage: %s", addUsage)
 		}
@@ -156,6 +170,9 @@ matches: %v
 				values = append(values, arg)
 			}
 		}
+		if cmd == "" {
+			return fmt.Errorf("Missing object type. Usage: %s", addUsage)
+		}
 		if len(values) == 0 {
 			return fmt.Errorf("No values given. Usage: %s", addUsage)
 		} else if len(values) > 1 {
@@ -163,7 +180,231 @@ matches: %v
 		}
 
 		values = strings.Split(values[0], ",")
-		fmt.Printf("Add %d %ss using values %v\n", times, cmd, values)
-		return nil
+		if cmd == "deployment" && (len(values) > 1 || times > 1) {
+			return fmt.Errorf("Multiple values are not allowed for deployments. Usage: %s", addUsage)
+		}
+		names, err := expandValues(cmd, values, times, currentNamingContext(project))
+		if err != nil {
+			return err
+		}
+
+		switch cmd {
+		case "server-group":
+			err = addServerGroups(project, names)
+		case "host":
+			err = addHosts(project, names)
+		case "server":
+			err = addServers(project, names)
+		case "deployment":
+			err = addDeployment(project, names[0])
+		case "user":
+			err = addUsers(project, names)
+		default:
+			return fmt.Errorf("Missing object type. Usage: %s", addUsage)
+		}
+		if err != nil {
+			return err
+		}
+		return project.Save()
 	},
 }
+
+// Expands the naming patterns in the values. Deployments are given as paths which are not
+// expanded. For users only the name before ":" is expanded, the password is kept as is.
+func expandValues(cmd string, values []string, times uint64, context namingContext) ([]string, error) {
+	switch cmd {
+	case "deployment":
+		return values, nil
+	case "user":
+		var patterns, passwords []string
+		for _, value := range values {
+			if index := strings.Index(value, ":"); index != -1 {
+				patterns = append(patterns, value[:index])
+				passwords = append(passwords, value[index:])
+			} else {
+				patterns = append(patterns, value)
+				passwords = append(passwords, "")
+			}
+		}
+		names, err := expandPatterns(patterns, times, context)
+		if err != nil {
+			return nil, err
+		}
+		// the names are expanded pattern by pattern for each time
+		for i := range names {
+			names[i] += passwords[i%len(passwords)]
+		}
+		return names, nil
+	default:
+		return expandPatterns(values, times, context)
+	}
+}
+
+func addServerGroups(project *model.Project, names []string) error {
+	var existing []string
+	for _, serverGroup := range project.ServerGroups {
+		existing = append(existing, serverGroup.Name)
+	}
+	if err := checkUnique("server group", existing, names); err != nil {
+		return err
+	}
+
+	// use the first profile and socket binding group of the domain template
+	domain, err := template.ReadDomain(project.Config.Templates.Domain)
+	if err != nil {
+		return err
+	}
+	var profile, socketBinding string
+	if len(domain.Profiles) > 0 {
+		profile = domain.Profiles[0].Name
+	}
+	if len(domain.SocketBindingGroups) > 0 {
+		socketBinding = domain.SocketBindingGroups[0].Name
+	}
+
+	for _, name := range names {
+		project.ServerGroups = append(project.ServerGroups, model.ServerGroup{
+			Name:          name,
+			Profile:       profile,
+			SocketBinding: socketBinding,
+			Deployments:   []model.Deployment{},
+		})
+	}
+	fmt.Printf("Added server groups %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+func addHosts(project *model.Project, names []string) error {
+	var existing []string
+	for _, host := range project.Hosts {
+		existing = append(existing, host.Name)
+	}
+	if err := checkUnique("host", existing, names); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		project.Hosts = append(project.Hosts, model.Host{
+			Name:    name,
+			Servers: []model.Server{},
+		})
+	}
+	fmt.Printf("Added hosts %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+func addServers(project *model.Project, names []string) error {
+	host, ok := currentObject(project).(*model.Host)
+	if !ok {
+		return fmt.Errorf(`Servers can only be added to a host. Please change the context using "cd hosts[<name>]".`)
+	}
+	var existing []string
+	var portOffset = -ports.OffsetStep
+	for _, server := range host.Servers {
+		existing = append(existing, server.Name)
+		if server.PortOffset > portOffset {
+			portOffset = server.PortOffset
+		}
+	}
+	if err := checkUnique("server", existing, names); err != nil {
+		return err
+	}
+
+	// assign new servers to the first server group
+	var serverGroup string
+	if len(project.ServerGroups) > 0 {
+		serverGroup = project.ServerGroups[0].Name
+	}
+	for _, name := range names {
+		portOffset += ports.OffsetStep
+		host.Servers = append(host.Servers, model.Server{
+			Name:        name,
+			ServerGroup: serverGroup,
+			PortOffset:  portOffset,
+		})
+	}
+	fmt.Printf("Added servers %s to host %s\n", strings.Join(names, ", "), host.Name)
+	return nil
+}
+
+func addDeployment(project *model.Project, artifact string) error {
+	serverGroup, ok := currentObject(project).(*model.ServerGroup)
+	if !ok {
+		return fmt.Errorf(`Deployments can only be added to a server group. Please change the context using "cd server-groups[<name>]".`)
+	}
+
+	// points are replaced to prevent naming problems when using paths
+	runtimeName := filepath.Base(artifact)
+	name := strings.Replace(runtimeName, ".", "-", -1)
+	var existing []string
+	for _, deployment := range serverGroup.Deployments {
+		existing = append(existing, deployment.Name)
+	}
+	if err := checkUnique("deployment", existing, []string{name}); err != nil {
+		return err
+	}
+
+	serverGroup.Deployments = append(serverGroup.Deployments, model.Deployment{
+		Name:        name,
+		RuntimeName: runtimeName,
+		Path:        artifact,
+	})
+	fmt.Printf("Added deployment %s to server group %s\n", name, serverGroup.Name)
+	return nil
+}
+
+func addUsers(project *model.Project, credentials []string) error {
+	var users []model.User
+	var names []string
+	for _, credential := range credentials {
+		parts := strings.SplitN(credential, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf(`Invalid user "%s": Please specify username and password as "username:password".`, credential)
+		}
+		users = append(users, model.User{Name: parts[0], Password: parts[1]})
+		names = append(names, parts[0])
+	}
+	existing := []string{project.Config.ConsoleUser.Name, project.Config.DomainUser.Name}
+	for _, user := range project.Users {
+		existing = append(existing, user.Name)
+	}
+	if err := checkUnique("user", existing, names); err != nil {
+		return err
+	}
+
+	project.Users = append(project.Users, users...)
+	fmt.Printf("Added users %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+// Makes sure that the names are unique within the batch and that none of the names is already
+// used. All collisions are reported at once.
+func checkUnique(kind string, existing []string, names []string) error {
+	var collisions []string
+	var occurrences = make(map[string]int)
+	var reported = make(map[string]bool)
+	for _, name := range names {
+		occurrences[name]++
+	}
+	for _, name := range names {
+		if reported[name] {
+			continue
+		}
+		if contains(existing, name) {
+			collisions = append(collisions, fmt.Sprintf(`"%s" already exists`, name))
+		} else if occurrences[name] > 1 {
+			collisions = append(collisions, fmt.Sprintf(`"%s" would be added %d times`, name, occurrences[name]))
+		} else {
+			continue
+		}
+		reported[name] = true // report each name only once
+	}
+
+	if len(collisions) == 1 && len(names) == 1 {
+		return fmt.Errorf(`A %s named "%s" already exists.`, kind, names[0])
+	} else if len(collisions) > 0 {
+		return fmt.Errorf("Unable to add %ss. Nothing was added due to the following name collisions:\n\n    %s",
+			kind, strings.Join(collisions, "\n    "))
+	}
+	return nil
+}
@@ -35,7 +35,9 @@ func init() {
 	Registry.Add(set)
 	Registry.Add(rm)
 	Registry.Add(validate)
-	Registry.Add(docker)
+	Registry.Add(portsCmd)
+	Registry.Add(generateCmd)
+	Registry.Add(dockerCmd)
 	Registry.Add(exit)
 	Registry.Add(help)
 }
@@ -57,8 +59,44 @@ type Command struct {
 
 // ------------------------------------------------------ functions used by several commands
 
+// Splits the command line at whitespace which is not part of an index, a JSON value or a
+// quoted string. Thus paths like "servers[?server-group == prod]" and values like
+// {"name": "jvm"} are kept together.
+func Tokens(cmdline string) []string {
+	var tokens []string
+	var token []rune
+	var depth int
+	var quoted, escaped bool
+	for _, c := range cmdline {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '{':
+			depth++
+		case (c == ']' || c == '}') && depth > 0:
+			depth--
+		case (c == ' ' || c == '\t') && depth == 0:
+			if len(token) != 0 {
+				tokens = append(tokens, string(token))
+				token = nil
+			}
+			continue
+		}
+		token = append(token, c)
+	}
+	if len(token) != 0 {
+		tokens = append(tokens, string(token))
+	}
+	return tokens
+}
+
 func completion(project *model.Project, query, cmdline string, kinds []reflect.Kind) ([]string, int) {
-	tokens := strings.Fields(cmdline)
+	tokens := Tokens(cmdline)
 	if len(tokens) == 1 && query == "" {
 		// just the command was given, return matches based on the current path
 		return keys(children(project, path.CurrentPath, kinds)), 0
@@ -107,7 +145,11 @@ func matchesFor(project *model.Project, context path.Path, segment string, kinds
 		//-+-+-+-+-
 		//`, openBracket, beforeIndex, index)
 
-		if openBracket {
+		if openBracket && !strings.HasPrefix(index, `"`) && strings.ContainsAny(index, "*?") {
+			// it's an unclosed glob: keep it as is and preview the number of matches
+			return globPreview(project, context, beforeIndex, index), 0
+
+		} else if openBracket {
 			// it's an unclosed index
 			if index == "" {
 				// return both numeric and alphanumeric matches
@@ -157,6 +199,26 @@ func matchesFor(project *model.Project, context path.Path, segment string, kinds
 	}
 }
 
+// Returns the glob itself and a hint with the number of objects it currently matches. As
+// there's no common prefix, readline keeps the glob and lists both entries.
+func globPreview(project *model.Project, context path.Path, name string, glob string) []string {
+	pth, err := path.Parse(name + "[" + glob + "]")
+	if err != nil {
+		return nil
+	}
+	targets, err := context.Append(pth).ResolveAll(project)
+	if err != nil {
+		return nil
+	}
+	switch len(targets) {
+	case 0:
+		return []string{glob, "(no matches)"}
+	case 1:
+		return []string{glob, "(1 match)"}
+	}
+	return []string{glob, fmt.Sprintf("(%d matches)", len(targets))}
+}
+
 func children(project *model.Project, context path.Path, kinds []reflect.Kind) map[string]reflect.Kind {
 	var matches = make(map[string]reflect.Kind)
 
@@ -209,9 +271,10 @@ func names(project *model.Project, context path.Path, name string, index string)
 		element := slice.Index(i)
 		value, err := reflections.GetField(element.Interface(), "Name")
 		if err == nil {
+			// names are quoted if necessary and match both quoted and unquoted input
 			strValue := value.(string)
-			if strings.HasPrefix(strValue, index) {
-				matches = append(matches, strValue)
+			if strings.HasPrefix(strValue, index) || strings.HasPrefix(path.QuoteName(strValue), index) {
+				matches = append(matches, path.QuoteName(strValue))
 			}
 		}
 	}
@@ -247,6 +310,20 @@ func getSlice(project *model.Project, context path.Path, name string) reflect.Va
 	return slice
 }
 
+// Returns a pointer to the object the current context points to or nil if the current
+// context cannot be resolved.
+func currentObject(project *model.Project) interface{} {
+	targets, err := path.CurrentPath.ResolveAll(project)
+	if err != nil || len(targets) != 1 {
+		return nil
+	}
+	value := targets[0].Value
+	if value.Kind() != reflect.Ptr && value.CanAddr() {
+		value = value.Addr()
+	}
+	return value.Interface()
+}
+
 func keys(m map[string]reflect.Kind) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
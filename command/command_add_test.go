@@ -0,0 +1,118 @@
+package command
+
+import (
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	"github.com/hpehl/whatunga/template"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// ------------------------------------------------------ setup
+
+type CommandAddSuite struct {
+	project *model.Project
+}
+
+func (s *CommandAddSuite) SetUpTest(c *C) {
+	domain := filepath.Join(c.MkDir(), "domain.xml")
+	data, err := template.Asset("templates/wildfly/8.1/domain.xml")
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(domain, data, model.FilePerm), IsNil)
+
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		Config: model.Config{
+			Templates: model.Templates{Domain: domain},
+		},
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "main-server-group"},
+		},
+		Hosts: []model.Host{
+			model.Host{Name: "master", Servers: []model.Server{
+				model.Server{Name: "server0", PortOffset: 100},
+			}},
+		},
+	}
+	path.CurrentPath = path.Path{}
+}
+
+func (s *CommandAddSuite) TearDownTest(_ *C) {
+	path.CurrentPath = path.Path{}
+}
+
+var _ = Suite(&CommandAddSuite{})
+
+// ------------------------------------------------------ add tests
+
+func (s *CommandAddSuite) TestAddServerGroups(c *C) {
+	c.Assert(addServerGroups(s.project, []string{"other-server-group"}), IsNil)
+	c.Assert(len(s.project.ServerGroups), Equals, 2)
+	c.Assert(s.project.ServerGroups[1].Name, Equals, "other-server-group")
+	c.Assert(s.project.ServerGroups[1].Profile, Equals, "default")
+	c.Assert(s.project.ServerGroups[1].SocketBinding, Equals, "standard-sockets")
+}
+
+func (s *CommandAddSuite) TestAddHosts(c *C) {
+	c.Assert(addHosts(s.project, []string{"slave0", "slave1"}), IsNil)
+	c.Assert(len(s.project.Hosts), Equals, 3)
+	c.Assert(s.project.Hosts[1].Name, Equals, "slave0")
+	c.Assert(s.project.Hosts[2].Name, Equals, "slave1")
+}
+
+func (s *CommandAddSuite) TestAddServers(c *C) {
+	path.CurrentPath, _ = path.Parse("hosts[master]")
+	c.Assert(addServers(s.project, []string{"server1", "server2"}), IsNil)
+
+	servers := s.project.Hosts[0].Servers
+	c.Assert(len(servers), Equals, 3)
+	c.Assert(servers[1], DeepEquals, model.Server{Name: "server1", ServerGroup: "main-server-group", PortOffset: 150})
+	c.Assert(servers[2], DeepEquals, model.Server{Name: "server2", ServerGroup: "main-server-group", PortOffset: 200})
+}
+
+func (s *CommandAddSuite) TestAddDeployment(c *C) {
+	path.CurrentPath, _ = path.Parse("server-groups[0]")
+	c.Assert(addDeployment(s.project, "deployments/ticketmonster.ear"), IsNil)
+
+	c.Assert(s.project.ServerGroups[0].Deployments, DeepEquals, []model.Deployment{
+		model.Deployment{Name: "ticketmonster-ear", RuntimeName: "ticketmonster.ear", Path: "deployments/ticketmonster.ear"},
+	})
+}
+
+func (s *CommandAddSuite) TestAddUsers(c *C) {
+	c.Assert(addUsers(s.project, []string{"foo:bar", "monitor:pass:word"}), IsNil)
+	c.Assert(s.project.Users, DeepEquals, []model.User{
+		model.User{Name: "foo", Password: "bar"},
+		model.User{Name: "monitor", Password: "pass:word"},
+	})
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *CommandAddSuite) TestAddServerOutsideHost(c *C) {
+	err := addServers(s.project, []string{"server1"})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Servers can only be added to a host. Please change the context using "cd hosts[<name>]".`)
+}
+
+func (s *CommandAddSuite) TestAddDeploymentOutsideServerGroup(c *C) {
+	path.CurrentPath, _ = path.Parse("hosts[master]")
+	err := addDeployment(s.project, "foo.war")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Deployments can only be added to a server group. Please change the context using "cd server-groups[<name>]".`)
+}
+
+func (s *CommandAddSuite) TestAddExistingHost(c *C) {
+	err := addHosts(s.project, []string{"master"})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `A host named "master" already exists.`)
+	c.Assert(len(s.project.Hosts), Equals, 1)
+}
+
+func (s *CommandAddSuite) TestAddInvalidUser(c *C) {
+	err := addUsers(s.project, []string{"foo"})
+	c.Assert(err, NotNil)
+	c.Assert(len(s.project.Users), Equals, 0)
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	. "gopkg.in/check.v1"
+)
+
+// ------------------------------------------------------ setup
+
+type CommandCompletionSuite struct {
+	project *model.Project
+}
+
+func (s *CommandCompletionSuite) SetUpTest(_ *C) {
+	s.project = &model.Project{
+		Hosts: []model.Host{
+			model.Host{Name: "master", Servers: []model.Server{
+				model.Server{Name: "web-1"},
+				model.Server{Name: "web-2"},
+				model.Server{Name: "batch"},
+			}},
+			model.Host{Name: "slave", Servers: []model.Server{
+				model.Server{Name: "app.v2"},
+				model.Server{Name: "app-v3"},
+			}},
+		},
+	}
+}
+
+var _ = Suite(&CommandCompletionSuite{})
+
+// ------------------------------------------------------ completion tests
+
+func (s *CommandCompletionSuite) TestNames(c *C) {
+	context, _ := path.Parse("hosts[master]")
+	matches, appendChar := matchesFor(s.project, context, "servers[we", nil)
+	c.Assert(matches, DeepEquals, []string{"web-1", "web-2"})
+	c.Assert(appendChar, Equals, 0)
+}
+
+func (s *CommandCompletionSuite) TestQuotedNames(c *C) {
+	context, _ := path.Parse("hosts[slave]")
+	matches, _ := matchesFor(s.project, context, "servers[app", nil)
+	c.Assert(matches, DeepEquals, []string{`"app.v2"`, "app-v3"})
+
+	matches, appendChar := matchesFor(s.project, context, `servers["app.`, nil)
+	c.Assert(matches, DeepEquals, []string{`"app.v2"`})
+	c.Assert(appendChar, Equals, int(']'))
+}
+
+func (s *CommandCompletionSuite) TestGlobPreview(c *C) {
+	context, _ := path.Parse("hosts[master]")
+	matches, appendChar := matchesFor(s.project, context, "servers[web-*", nil)
+	c.Assert(matches, DeepEquals, []string{"web-*", "(2 matches)"})
+	c.Assert(appendChar, Equals, 0)
+
+	matches, _ = matchesFor(s.project, context, "servers[b?tch", nil)
+	c.Assert(matches, DeepEquals, []string{"b?tch", "(1 match)"})
+	matches, _ = matchesFor(s.project, context, "servers[db-*", nil)
+	c.Assert(matches, DeepEquals, []string{"db-*", "(no matches)"})
+}
+
+// ------------------------------------------------------ tokens tests
+
+func (s *CommandCompletionSuite) TestTokens(c *C) {
+	c.Assert(Tokens("  ls  hosts[0] "), DeepEquals, []string{"ls", "hosts[0]"})
+	c.Assert(Tokens(`rm servers[?server-group == "prod eu" && auto-start == true]`), DeepEquals,
+		[]string{"rm", `servers[?server-group == "prod eu" && auto-start == true]`})
+	c.Assert(Tokens(`set jvm {"name": "jvm", "options": ["-server", "-d64"]}`), DeepEquals,
+		[]string{"set", "jvm", `{"name": "jvm", "options": ["-server", "-d64"]}`})
+	c.Assert(Tokens(`set name "a \" b"`), DeepEquals, []string{"set", "name", `"a \" b"`})
+	c.Assert(Tokens(" "), IsNil)
+}
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+)
+
+// ------------------------------------------------------ setup
+
+type CommandPatternSuite struct {
+	project *model.Project
+	context namingContext
+}
+
+func (s *CommandPatternSuite) SetUpSuite(_ *C) {
+	s.project = &model.Project{
+		Name:    "foo",
+		Version: "1.0",
+		Hosts:   []model.Host{model.Host{Name: "master"}},
+	}
+	s.context = namingContext{project: s.project, host: &s.project.Hosts[0]}
+}
+
+var _ = Suite(&CommandPatternSuite{})
+
+// ------------------------------------------------------ pattern tests
+
+func (s *CommandPatternSuite) TestExpandPlain(c *C) {
+	assertExpand(c, "server", 3, s.context, "server")
+}
+
+func (s *CommandPatternSuite) TestExpandVariables(c *C) {
+	assertExpand(c, "%w-%v-%h", 0, s.context, "foo-1.0-master")
+	assertExpand(c, "100%%", 0, s.context, "100%")
+}
+
+func (s *CommandPatternSuite) TestExpandCounter(c *C) {
+	assertExpand(c, "server%c", 0, s.context, "server0")
+	assertExpand(c, "server%c", 12, s.context, "server12")
+	assertExpand(c, "server%1c", 0, s.context, "server1")
+	assertExpand(c, "server%10c", 5, s.context, "server15")
+	assertExpand(c, "server%00c", 5, s.context, "server05")
+	assertExpand(c, "server%001c", 0, s.context, "server001")
+	assertExpand(c, "server%01c", 100, s.context, "server101")
+}
+
+func (s *CommandPatternSuite) TestExpandPatterns(c *C) {
+	names, err := expandPatterns([]string{"%w-%h-server-%1c"}, 3, s.context)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"foo-master-server-1", "foo-master-server-2", "foo-master-server-3"})
+
+	names, err = expandPatterns([]string{"a%c", "b%c"}, 2, s.context)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"a0", "b1", "a2", "b3"})
+}
+
+func (s *CommandPatternSuite) TestExpandValues(c *C) {
+	names, err := expandValues("user", []string{"user%1c:p%ss", "admin:100%"}, 2, s.context)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"user1:p%ss", "admin:100%", "user3:p%ss", "admin:100%"})
+
+	names, err = expandValues("user", []string{"%w"}, 1, s.context)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"foo"})
+
+	names, err = expandValues("deployment", []string{"deployments/app%20.war"}, 1, s.context)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"deployments/app%20.war"})
+
+	names, err = expandValues("server", []string{"%h-%c"}, 2, s.context)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"master-0", "master-1"})
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *CommandPatternSuite) TestExpandUnknown(c *C) {
+	expectExpandError(c, "server%x", s.context, `Unknown variable "%x" in pattern "server%x".`)
+	expectExpandError(c, "server%2h", s.context, `Unknown variable "%2h" in pattern "server%2h".`)
+	expectExpandError(c, "server%", s.context, `Incomplete variable "%" at the end of pattern "server%".`)
+	expectExpandError(c, "server%12", s.context, `Incomplete variable "%12" at the end of pattern "server%12".`)
+}
+
+func (s *CommandPatternSuite) TestExpandContext(c *C) {
+	root := namingContext{project: s.project}
+	expectExpandError(c, "%h-server", root, `Variable "%h" in pattern "%h-server" is only applicable when adding servers to a host.`)
+	expectExpandError(c, "%g-app", root, `Variable "%g" in pattern "%g-app" is only applicable if the current context is a server group.`)
+}
+
+func (s *CommandPatternSuite) TestCollisions(c *C) {
+	c.Assert(checkUnique("server", []string{"a"}, []string{"b", "c"}), IsNil)
+
+	err := checkUnique("server", []string{"a"}, []string{"a"})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `A server named "a" already exists.`)
+
+	err = checkUnique("server", []string{"a"}, []string{"a", "b", "c", "b", "b", "a"})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Unable to add servers. Nothing was added due to the following name collisions:
+
+    "a" already exists
+    "b" would be added 3 times`)
+}
+
+// ------------------------------------------------------ helper functions
+
+func assertExpand(c *C, pattern string, counter int, context namingContext, expected string) {
+	name, err := expandPattern(pattern, counter, context)
+	c.Assert(err, IsNil)
+	c.Assert(name, Equals, expected)
+}
+
+func expectExpandError(c *C, pattern string, context namingContext, why string) {
+	name, err := expandPattern(pattern, 0, context)
+	c.Assert(name, Equals, "")
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, why)
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	. "gopkg.in/check.v1"
+)
+
+// ------------------------------------------------------ setup
+
+type CommandRmSuite struct {
+	project *model.Project
+}
+
+func (s *CommandRmSuite) SetUpTest(_ *C) {
+	s.project = &model.Project{
+		Config: model.Config{
+			ConsoleUser: model.User{Name: "admin", Password: "passw0rd_"},
+			DomainUser:  model.User{Name: "dc", Password: "passw0rd_"},
+		},
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "dev"},
+			model.ServerGroup{Name: "prod"},
+		},
+		Hosts: []model.Host{
+			model.Host{Name: "master", Servers: []model.Server{
+				model.Server{Name: "server0", ServerGroup: "prod"},
+				model.Server{Name: "server1", ServerGroup: "dev"},
+			}},
+			model.Host{Name: "slave", Servers: []model.Server{
+				model.Server{Name: "server2", ServerGroup: "prod"},
+			}},
+		},
+	}
+	path.CurrentPath = path.Path{}
+}
+
+func (s *CommandRmSuite) TearDownTest(_ *C) {
+	path.CurrentPath = path.Path{}
+}
+
+var _ = Suite(&CommandRmSuite{})
+
+// ------------------------------------------------------ rm tests
+
+func (s *CommandRmSuite) TestDependentServers(c *C) {
+	pth, _ := path.Parse("server-groups[prod]")
+	targets, err := pth.ResolveAll(s.project)
+	c.Assert(err, IsNil)
+
+	dependents, err := dependentServers(s.project, targets)
+	c.Assert(err, IsNil)
+	c.Assert(len(dependents), Equals, 2)
+	c.Assert(dependents[0].String(), Equals, "hosts[master].servers[server0]")
+	c.Assert(dependents[1].String(), Equals, "hosts[slave].servers[server2]")
+}
+
+func (s *CommandRmSuite) TestNoDependentServers(c *C) {
+	pth, _ := path.Parse("hosts[master]")
+	targets, err := pth.ResolveAll(s.project)
+	c.Assert(err, IsNil)
+
+	dependents, err := dependentServers(s.project, targets)
+	c.Assert(err, IsNil)
+	c.Assert(len(dependents), Equals, 0)
+}
+
+func (s *CommandRmSuite) TestRemoveServers(c *C) {
+	c.Assert(removeObjects(s.project, "hosts[:].servers[?server-group==prod]", false), IsNil)
+	c.Assert(s.project.Hosts[0].Servers, DeepEquals, []model.Server{model.Server{Name: "server1", ServerGroup: "dev"}})
+	c.Assert(s.project.Hosts[1].Servers, HasLen, 0)
+}
+
+func (s *CommandRmSuite) TestRemoveServerGroupWithoutDependents(c *C) {
+	s.project.Hosts[0].Servers = s.project.Hosts[0].Servers[:1]
+	c.Assert(removeObjects(s.project, "server-groups[dev]", false), IsNil)
+	c.Assert(s.project.ServerGroups, DeepEquals, []model.ServerGroup{model.ServerGroup{Name: "prod"}})
+}
+
+func (s *CommandRmSuite) TestRemoveServerGroupForce(c *C) {
+	c.Assert(removeObjects(s.project, "server-groups[prod]", true), IsNil)
+	c.Assert(s.project.ServerGroups, DeepEquals, []model.ServerGroup{model.ServerGroup{Name: "dev"}})
+	c.Assert(s.project.Hosts[0].Servers, DeepEquals, []model.Server{model.Server{Name: "server1", ServerGroup: "dev"}})
+	c.Assert(s.project.Hosts[1].Servers, HasLen, 0)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *CommandRmSuite) TestRemoveServerGroupWithDependents(c *C) {
+	err := removeObjects(s.project, "server-groups[prod]", false)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Unable to remove server groups which are referenced by these servers:
+
+    hosts[master].servers[server0]
+    hosts[slave].servers[server2]
+
+Use "rm --force server-groups[prod]" to remove the servers as well.`)
+	c.Assert(s.project.ServerGroups, HasLen, 2)
+	c.Assert(s.project.Hosts[0].Servers, HasLen, 2)
+	c.Assert(s.project.Hosts[1].Servers, HasLen, 1)
+}
+
+func (s *CommandRmSuite) TestRemoveMandatoryUsers(c *C) {
+	for _, user := range []string{"config.console-user", "config.domain-user"} {
+		for _, force := range []bool{false, true} {
+			err := removeObjects(s.project, user, force)
+			c.Assert(err, ErrorMatches, `Unable to remove "`+user+`": The console and domain user are mandatory.`)
+		}
+	}
+	c.Assert(s.project.Config.ConsoleUser, Equals, model.User{Name: "admin", Password: "passw0rd_"})
+	c.Assert(s.project.Config.DomainUser, Equals, model.User{Name: "dc", Password: "passw0rd_"})
+}
+
+func (s *CommandRmSuite) TestRemoveNoMatch(c *C) {
+	err := removeObjects(s.project, "hosts[:].servers[?port-offset>=500]", false)
+	c.Assert(err, ErrorMatches, "The path does not match any object.")
+}
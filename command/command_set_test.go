@@ -0,0 +1,94 @@
+package command
+
+import (
+	"github.com/hpehl/whatunga/path"
+	. "gopkg.in/check.v1"
+	"reflect"
+)
+
+// ------------------------------------------------------ setup
+
+type CommandSetSuite struct {
+	targets []path.Target
+	names   []path.Target
+}
+
+func (s *CommandSetSuite) SetUpSuite(_ *C) {
+	for _, p := range []string{"servers[0]", "servers[1]", "servers[2]"} {
+		pth, _ := path.Parse(p + ".port-offset")
+		s.targets = append(s.targets, path.Target{Path: pth, Value: reflect.ValueOf(new(int)).Elem()})
+		pth, _ = path.Parse(p + ".name")
+		s.names = append(s.names, path.Target{Path: pth, Value: reflect.ValueOf(new(string)).Elem()})
+	}
+}
+
+var _ = Suite(&CommandSetSuite{})
+
+// ------------------------------------------------------ split tests
+
+func (s *CommandSetSuite) TestSplitSimple(c *C) {
+	c.Assert(splitValues("true"), DeepEquals, []string{"true"})
+	c.Assert(splitValues("true,false,true"), DeepEquals, []string{"true", "false", "true"})
+	c.Assert(splitValues("a,,b"), DeepEquals, []string{"a", "", "b"})
+}
+
+func (s *CommandSetSuite) TestSplitJson(c *C) {
+	jvm := `{"name":"s2jvm","heap":{"initial":"1GB","max":"2GB"},"options":["-server","-d64"]}`
+	c.Assert(splitValues(jvm), DeepEquals, []string{jvm})
+	c.Assert(splitValues(jvm+",null"), DeepEquals, []string{jvm, "null"})
+	c.Assert(splitValues(`"a,b",c`), DeepEquals, []string{`"a,b"`, "c"})
+	c.Assert(splitValues(`"a\",b",c`), DeepEquals, []string{`"a\",b"`, "c"})
+}
+
+// ------------------------------------------------------ distribute tests
+
+func (s *CommandSetSuite) TestDistributeBroadcast(c *C) {
+	values, err := distribute([]string{"true"}, s.targets)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"true", "true", "true"})
+}
+
+func (s *CommandSetSuite) TestDistributeOneToOne(c *C) {
+	values, err := distribute([]string{"a", "b", "c"}, s.targets)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"a", "b", "c"})
+}
+
+func (s *CommandSetSuite) TestDistributeSequence(c *C) {
+	values, err := distribute([]string{"0+20"}, s.targets)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"0", "20", "40"})
+
+	values, err = distribute([]string{"100+-50"}, s.targets)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"100", "50", "0"})
+}
+
+func (s *CommandSetSuite) TestDistributeSequenceLiteral(c *C) {
+	// strings like versions are never expanded
+	values, err := distribute([]string{"8+1"}, s.names)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"8+1", "8+1", "8+1"})
+
+	mixed := []path.Target{s.targets[0], s.names[1]}
+	values, err = distribute([]string{"1+2"}, mixed)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"1+2", "1+2"})
+}
+
+func (s *CommandSetSuite) TestDistributeMismatch(c *C) {
+	values, err := distribute([]string{"a", "b"}, s.targets)
+	c.Assert(values, IsNil)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `2 values given, but the path matches 3 objects:
+
+    servers[0].port-offset
+    servers[1].port-offset
+    servers[2].port-offset`)
+}
+
+func (s *CommandSetSuite) TestDistributeNoTargets(c *C) {
+	values, err := distribute([]string{"a"}, nil)
+	c.Assert(values, IsNil)
+	c.Assert(err, NotNil)
+}
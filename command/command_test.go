@@ -0,0 +1,9 @@
+package command
+
+import (
+	. "gopkg.in/check.v1"
+	"testing"
+)
+
+// triggers all tests in this package
+func TestCommand(t *testing.T) { TestingT(t) }
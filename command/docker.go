@@ -2,50 +2,166 @@ package command
 
 import (
 	"fmt"
+	"github.com/hpehl/whatunga/docker"
+	"github.com/hpehl/whatunga/generate"
 	"github.com/hpehl/whatunga/model"
+	"os"
+	"os/signal"
 	"strings"
 )
 
-var dockerSubCommands = []string{"create", "push", "start"}
-var dockerUsage = "docker " + strings.Join(dockerSubCommands, "|")
+var dockerSubCommands = []string{"create", "push", "start", "stop", "status", "logs", "rm"}
+var followOption = "-f"
+var mountOption = "--mount"
+var dockerUsage = "docker " + strings.Join(dockerSubCommands, "|") + " [host] [" + followOption + "] [" + mountOption + "]"
 
-var docker = Command{
+var dockerCmd = Command{
 	"docker",
 	"Docker related commands",
 	dockerUsage,
 	`Docker related commands
 
-    - create: Creates docker images based on the current project model.
-    - start: Starts the docker images.`,
+    - create [` + mountOption + `]: Creates one docker image per host based on the
+      current project model. The images are named "<project>/<host>:<version>"
+      and contain the generated configuration files. The deployment artifacts
+      are copied to the content repository of the domain controller. Use
+      ` + mountOption + ` to reference the artifacts in the deployments folder of the
+      project instead. This folder is then mounted into the containers by
+      "docker start", so you can redeploy without building new images. The
+      images are built using the remote API configured in
+      "config.docker-remote-api".
+    - push: Tags the images for the registry in "config.registry" and pushes
+      them as "<registry>/<repository>/<project>/<host>:<version>". Failed
+      pushes are retried.
+    - start: Creates and starts one container per host. The domain
+      controller is started first. As soon as its management port accepts
+      connections, the slaves are started. If a container fails to start,
+      all containers are removed again. The containers are connected to a
+      network named after the project and are reachable by their host name.
+      The deployments folder is mounted read-only if the images have been
+      created using ` + mountOption + `.
+    - stop: Stops all containers of the project.
+    - status: Shows the host, version, container ID, state and published
+      ports of each container.
+    - logs [host] [` + followOption + `]: Shows the logs of the given host or of all hosts.
+      Each line is prefixed with the host name. Use ` + followOption + ` to follow the
+      logs until the containers stop or you press Ctrl-C.
+    - rm: Removes all containers and the network of the project.
+
+The containers are labeled with the project name and version. Only containers
+of the current project are touched by these commands. Containers of other
+versions are included, so they can still be stopped and removed after the
+version has been changed.`,
 	// tab completer
-	func(_ *model.Project, query, _ string) ([]string, int) {
+	func(project *model.Project, query, cmdline string) ([]string, int) {
+		var candidates []string
+		tokens := strings.Fields(cmdline)
+		if len(tokens) == 1 || (len(tokens) == 2 && query != "") {
+			candidates = dockerSubCommands
+		} else if tokens[1] == "logs" {
+			for _, host := range project.Hosts {
+				candidates = append(candidates, host.Name)
+			}
+			candidates = append(candidates, followOption)
+		} else if tokens[1] == "create" {
+			candidates = []string{mountOption}
+		}
 		var results []string
-		for _, subCommand := range dockerSubCommands {
-			if strings.HasPrefix(subCommand, query) {
-				results = append(results, subCommand)
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, query) && !contains(tokens[1:], candidate) {
+				results = append(results, candidate)
 			}
 		}
 		return results, ' '
 	},
 	// action
-	func(_ *model.Project, args []string) error {
+	func(project *model.Project, args []string) error {
 		if len(args) == 0 {
 			return fmt.Errorf("Missing argument. Usage: %s", dockerUsage)
 		}
-		if len(args) > 1 {
+		if !contains(dockerSubCommands, args[0]) {
+			return fmt.Errorf(`Unsupported argument "%s". Usage: %s`, args[0], dockerUsage)
+		}
+		mount := args[0] == "create" && len(args) == 2 && args[1] == mountOption
+		if len(args) > 1 && args[0] != "logs" && !mount {
 			return fmt.Errorf("Too many arguments. Usage: %s", dockerUsage)
 		}
-		// TODO not yet implemented
+		client, err := docker.NewClient(project.Config.DockerRemoteAPI)
+		if err != nil {
+			return err
+		}
+
 		switch args[0] {
 		case "create":
-			fmt.Printf("Docker create...\n")
+			return dockerCreate(client, project, mount)
 		case "push":
-			fmt.Printf("Docker push...\n")
+			return docker.Push(client, project, os.Stdout)
 		case "start":
-			fmt.Printf("Docker start...\n")
-		default:
-			return fmt.Errorf(`Unsupported argument "%s". Usage: %s`, args[0], dockerUsage)
+			return docker.Start(client, project, os.Stdout)
+		case "stop":
+			return docker.Stop(client, project, os.Stdout)
+		case "status":
+			return docker.Status(client, project, os.Stdout)
+		case "logs":
+			return dockerLogs(client, project, args[1:])
+		case "rm":
+			return docker.Remove(client, project, os.Stdout)
 		}
 		return nil
 	},
 }
+
+func dockerCreate(client *docker.Client, project *model.Project, mount bool) error {
+	if len(project.Hosts) == 0 {
+		return fmt.Errorf("The project does not contain any hosts.")
+	}
+	// generate all build contexts first to fail early
+	var err error
+	contexts := make([]map[string][]byte, len(project.Hosts))
+	for i, host := range project.Hosts {
+		if contexts[i], err = generate.BuildContext(project, host, mount); err != nil {
+			return err
+		}
+	}
+	for i, host := range project.Hosts {
+		image := docker.ImageName(project, host)
+		fmt.Printf("Building image %s for host %s\n", image, host.Name)
+		if err := client.Build(image, contexts[i], os.Stdout); err != nil {
+			return fmt.Errorf("Unable to build image %s: %s", image, err)
+		}
+	}
+	return nil
+}
+
+func dockerLogs(client *docker.Client, project *model.Project, args []string) error {
+	var hosts []string
+	var follow bool
+	for _, arg := range args {
+		if arg == followOption {
+			follow = true
+		} else {
+			hosts = append(hosts, arg)
+		}
+	}
+	if len(hosts) > 1 {
+		return fmt.Errorf("Too many arguments. Usage: %s", dockerUsage)
+	}
+
+	// stop following the logs on Ctrl-C
+	cancel := make(chan struct{})
+	if follow {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-interrupt:
+				close(cancel)
+			case <-done:
+			}
+		}()
+	}
+	return docker.Logs(client, project, hosts, follow, os.Stdout, cancel)
+}
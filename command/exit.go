@@ -19,7 +19,7 @@ var exit = Command{
 	},
 	// action
 	func(_ *model.Project, _ []string) error {
-		fmt.Println("Haere rā\n")
+		fmt.Print("Haere rā\n\n")
 		return EXIT
 	},
 }
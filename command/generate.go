@@ -0,0 +1,149 @@
+package command
+
+import (
+	"fmt"
+	"github.com/hpehl/whatunga/docker"
+	"github.com/hpehl/whatunga/generate"
+	"github.com/hpehl/whatunga/model"
+	"path/filepath"
+	"strings"
+)
+
+var generateTargets = []string{"domain", "hosts", "users", "docker", "compose"}
+var generateUsage = "generate " + strings.Join(generateTargets, "|") + " [directory]"
+
+var generateCmd = Command{
+	"generate",
+	"Generates configuration files based on the project model.",
+	generateUsage,
+	`Generates configuration files based on the templates and the project model.
+The files are written to the given directory or to "` + generate.OutputDir + `" if no directory
+is specified.
+
+    - domain: Generates "domain.xml" based on the domain template. The server
+      groups and deployments of the template are replaced with the ones
+      from the project model.
+    - hosts:  Generates one "host-<name>.xml" per host. The host marked as
+      domain controller is based on the host master template, all other
+      hosts are based on the host slave template.
+    - users:  Generates the property files of the management and application
+      realm. The console and the domain user are added to the management
+      realm, all other users to the application realm.
+    - docker: Generates one build context per host in "<directory>/<host>".
+      Each build context contains a Dockerfile, the generated configuration
+      files and the distribution from the folder "` + generate.DownloadsFolder + `" which
+      matches the target of the project. The images can be built using
+      "docker build <directory>/<host>".
+    - compose: Generates the build contexts like "docker" and a
+      "` + docker.ComposeFile + `" with one service per host. The slaves are started as
+      soon as the domain controller is healthy. Use "docker-compose up" in
+      the directory to build and start the domain without the remote API.`,
+	// tab completer
+	func(_ *model.Project, query, cmdline string) ([]string, int) {
+		if len(strings.Fields(cmdline)) > 2 || (len(strings.Fields(cmdline)) == 2 && query == "") {
+			return nil, 0
+		}
+		var results []string
+		for _, target := range generateTargets {
+			if strings.HasPrefix(target, query) {
+				results = append(results, target)
+			}
+		}
+		return results, ' '
+	},
+	// action
+	func(project *model.Project, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("Missing argument. Usage: %s", generateUsage)
+		}
+		if len(args) > 2 {
+			return fmt.Errorf("Too many arguments. Usage: %s", generateUsage)
+		}
+		var dir = generate.OutputDir
+		if len(args) == 2 {
+			dir = args[1]
+		}
+
+		switch args[0] {
+		case "domain":
+			data, err := generate.Domain(project)
+			if err != nil {
+				return err
+			}
+			if err := generate.WriteFile(dir, "domain.xml", data); err != nil {
+				return err
+			}
+			fmt.Printf("Generated %s\n", filepath.Join(dir, "domain.xml"))
+		case "hosts":
+			if len(project.Hosts) == 0 {
+				return fmt.Errorf("The project does not contain any hosts.")
+			}
+			for _, host := range project.Hosts {
+				data, err := generate.Host(project, host)
+				if err != nil {
+					return err
+				}
+				filename := generate.HostFile(host)
+				if err := generate.WriteFile(dir, filename, data); err != nil {
+					return err
+				}
+				fmt.Printf("Generated %s\n", filepath.Join(dir, filename))
+			}
+		case "users":
+			files, err := generate.Users(project)
+			if err != nil {
+				return err
+			}
+			for _, filename := range generate.UserFiles {
+				if err := generate.WriteFile(dir, filename, files[filename]); err != nil {
+					return err
+				}
+				fmt.Printf("Generated %s\n", filepath.Join(dir, filename))
+			}
+		case "docker":
+			if err := generateBuildContexts(project, dir); err != nil {
+				return err
+			}
+		case "compose":
+			data, err := docker.Compose(project)
+			if err != nil {
+				return err
+			}
+			if err := generateBuildContexts(project, dir); err != nil {
+				return err
+			}
+			if err := generate.WriteFile(dir, docker.ComposeFile, data); err != nil {
+				return err
+			}
+			fmt.Printf("Generated %s\n", filepath.Join(dir, docker.ComposeFile))
+		default:
+			return fmt.Errorf(`Unsupported argument "%s". Usage: %s`, args[0], generateUsage)
+		}
+		return nil
+	},
+}
+
+// Writes the build context of each host to "<dir>/<host>".
+func generateBuildContexts(project *model.Project, dir string) error {
+	if len(project.Hosts) == 0 {
+		return fmt.Errorf("The project does not contain any hosts.")
+	}
+	// generate all build contexts first to fail early
+	contexts := make([]map[string][]byte, len(project.Hosts))
+	for i, host := range project.Hosts {
+		var err error
+		if contexts[i], err = generate.DistributionContext(project, host, false); err != nil {
+			return err
+		}
+	}
+	for i, host := range project.Hosts {
+		hostDir := filepath.Join(dir, host.Name)
+		for filename, data := range contexts[i] {
+			if err := generate.WriteFile(hostDir, filename, data); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Generated %s\n", hostDir)
+	}
+	return nil
+}
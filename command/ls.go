@@ -14,7 +14,13 @@ var ls = Command{
 	"ls",
 	"Lists the model of the current context or specified path",
 	lsUsage,
-	`Lists the model of the current context or specified path`,
+	`Lists the model of the current context or specified path. If the path
+contains ranges, globs, filters or descents, all matching objects are listed
+together with their path:
+
+    ls hosts[:].servers[1:].port-offset
+    ls hosts[:].servers[?server-group=="prod" && auto-start==true]
+    ls **.jvm`,
 	// tab completer
 	func(project *model.Project, query, cmdline string) ([]string, int) {
 		return completion(project, query, cmdline, []reflect.Kind{})
@@ -47,6 +53,24 @@ var ls = Command{
 			}
 		}
 
+		if context.IsMulti() {
+			targets, err := context.ResolveAll(project)
+			if err != nil {
+				return err
+			}
+			for index, target := range targets {
+				data, err := json.MarshalIndent(target.Value.Interface(), "", "  ")
+				if err != nil {
+					return err
+				}
+				if index > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s:\n%s\n", target, string(data))
+			}
+			return nil
+		}
+
 		obj, err := context.Resolve(project)
 		if err != nil {
 			return err
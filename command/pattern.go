@@ -0,0 +1,118 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"strconv"
+)
+
+// The objects which provide the values for the variables of a naming pattern.
+// Host and server group are nil unless the current context points to them.
+type namingContext struct {
+	project     *model.Project
+	host        *model.Host
+	serverGroup *model.ServerGroup
+}
+
+// Returns a naming context based on the current path.
+func currentNamingContext(project *model.Project) namingContext {
+	context := namingContext{project: project}
+	switch object := currentObject(project).(type) {
+	case *model.Host:
+		context.host = object
+	case *model.ServerGroup:
+		context.serverGroup = object
+	}
+	return context
+}
+
+// Expands each pattern times times. The counter is incremented for each expanded name.
+func expandPatterns(patterns []string, times uint64, context namingContext) ([]string, error) {
+	var names []string
+	var counter int
+	for i := uint64(0); i < times; i++ {
+		for _, pattern := range patterns {
+			name, err := expandPattern(pattern, counter, context)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			counter++
+		}
+	}
+	return names, nil
+}
+
+// Replaces the variables in the pattern:
+//
+//	%w    project name
+//	%v    project version
+//	%h    current host name
+//	%g    current server group name
+//	%[n]c counter starting at n (zero by default). A leading zero as in
+//	      %001c pads the counter to the number of digits given.
+//	%%    a literal percent sign
+func expandPattern(pattern string, counter int, context namingContext) (string, error) {
+	var buffer bytes.Buffer
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			buffer.WriteByte(pattern[i])
+			continue
+		}
+
+		// collect optional digits of a counter
+		start := i + 1
+		end := start
+		for end < len(pattern) && pattern[end] >= '0' && pattern[end] <= '9' {
+			end++
+		}
+		if end == len(pattern) {
+			return "", fmt.Errorf(`Incomplete variable "%s" at the end of pattern "%s".`, pattern[i:], pattern)
+		}
+		digits := pattern[start:end]
+		variable := pattern[i : end+1]
+
+		switch {
+		case pattern[end] == 'c':
+			var offset, width int
+			if digits != "" {
+				offset, _ = strconv.Atoi(digits)
+				if len(digits) > 1 && digits[0] == '0' {
+					width = len(digits)
+				}
+			}
+			buffer.WriteString(fmt.Sprintf("%0*d", width, offset+counter))
+
+		case digits != "":
+			return "", fmt.Errorf(`Unknown variable "%s" in pattern "%s".`, variable, pattern)
+
+		case pattern[end] == 'w':
+			buffer.WriteString(context.project.Name)
+
+		case pattern[end] == 'v':
+			buffer.WriteString(context.project.Version)
+
+		case pattern[end] == 'h':
+			if context.host == nil {
+				return "", fmt.Errorf(`Variable "%%h" in pattern "%s" is only applicable when adding servers to a host.`, pattern)
+			}
+			buffer.WriteString(context.host.Name)
+
+		case pattern[end] == 'g':
+			if context.serverGroup == nil {
+				return "", fmt.Errorf(`Variable "%%g" in pattern "%s" is only applicable if the current context is a server group.`, pattern)
+			}
+			buffer.WriteString(context.serverGroup.Name)
+
+		case pattern[end] == '%':
+			buffer.WriteByte('%')
+
+		default:
+			return "", fmt.Errorf(`Unknown variable "%s" in pattern "%s".`, variable, pattern)
+		}
+		i = end
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/ports"
+	"github.com/hpehl/whatunga/template"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var allOption = "--all"
+var portsUsage = "ports [" + allOption + "]"
+
+var portsCmd = Command{
+	"ports",
+	"Shows the effective ports of all hosts and servers.",
+	portsUsage,
+	`Shows the effective ports of all host controllers and servers. The ports of a
+server are calculated from the socket binding group of its server group and the
+port offset of the server. Ports which are used more than once on the same host
+are flagged.
+
+Per default only the well known ports (` + strings.Join(ports.WellKnown, ", ") + `)
+are shown. Use ` + allOption + ` to show the ports of all socket bindings.`,
+	// tab completer
+	func(_ *model.Project, query, _ string) ([]string, int) {
+		if strings.HasPrefix(allOption, query) {
+			return []string{allOption}, ' '
+		}
+		return nil, 0
+	},
+	// action
+	func(project *model.Project, args []string) error {
+		if len(args) > 1 || (len(args) == 1 && args[0] != allOption) {
+			return fmt.Errorf("Illegal argument. Usage: %s", portsUsage)
+		}
+		all := len(args) == 1
+
+		domain, err := template.ReadDomain(project.Config.Templates.Domain)
+		if err != nil {
+			return err
+		}
+		plan, err := ports.Plan(project, domain)
+		if err != nil {
+			return err
+		}
+		planConflicts := ports.Conflicts(plan)
+		conflicts := make(map[ports.Port][]string)
+		for _, conflict := range planConflicts {
+			conflicts[conflict.First] = append(conflicts[conflict.First], conflict.Second.Owner())
+			conflicts[conflict.Second] = append(conflicts[conflict.Second], conflict.First.Owner())
+		}
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(table, "HOST\tSERVER\tBINDING\tPORT\tCONFLICTS")
+		for _, port := range plan {
+			if !all && !port.IsWellKnown() && len(conflicts[port]) == 0 {
+				continue
+			}
+			server := port.Server
+			if server == "" {
+				server = "-"
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\n", port.Host, server, port.Binding, port.Port,
+				strings.Join(conflicts[port], ", "))
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+		if len(planConflicts) != 0 {
+			fmt.Printf("\n%d port conflict(s)\n", len(planConflicts))
+		}
+		return nil
+	},
+}
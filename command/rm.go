@@ -1,32 +1,164 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	"reflect"
+	"strings"
 )
 
-var rmUsage = "rm <path>"
+var forceOption = "--force"
+var rmUsage = "rm [" + forceOption + "] <path>"
+
+// the mandatory users of the configuration
+var mandatoryUsers = []string{"config.console-user", "config.domain-user"}
 
 var rm = Command{
 	"rm",
 	"Removes an object from the project model.",
 	rmUsage,
-	"Removes an object from the project model. TODO: Describe path for rm.",
+	`Removes one or several objects from the project model. The path must point
+to elements of a collection or to optional objects like a JVM. Use ranges,
+globs, filters or descents to remove several objects at once:
+
+    rm hosts[slave0]
+    rm hosts[master].servers[1:]
+    rm hosts[:].servers[?port-offset>=200]
+    rm server-groups[0].jvm
+    rm **.jvm
+
+Server groups which are still referenced by servers are not removed. Instead
+the referencing servers are listed. Use ` + forceOption + ` to remove these servers as well:
+
+    rm ` + forceOption + ` server-groups[prod]
+
+The console and domain user of the configuration cannot be removed.`,
 	// tab completer
-	func(_ *model.Project, _, _ string) ([]string, int) {
-		// TODO not yet implemented
-		return nil, 0
+	func(project *model.Project, query, cmdline string) ([]string, int) {
+		if strings.HasPrefix(query, "-") {
+			if strings.HasPrefix(forceOption, query) {
+				return []string{forceOption}, ' '
+			}
+			return nil, 0
+		}
+		var tokens []string
+		for _, token := range strings.Fields(cmdline) {
+			if token != forceOption {
+				tokens = append(tokens, token)
+			}
+		}
+		return completion(project, query, strings.Join(tokens, " "), []reflect.Kind{reflect.Struct, reflect.Slice, reflect.Ptr})
 	},
 	// action
-	func(_ *model.Project, args []string) error {
-		if len(args) == 0 {
+	func(project *model.Project, args []string) error {
+		var force bool
+		var paths []string
+		for _, arg := range args {
+			if arg == forceOption {
+				force = true
+			} else {
+				paths = append(paths, arg)
+			}
+		}
+		if len(paths) == 0 {
 			return fmt.Errorf("Missing arguments. Usage: %s", rmUsage)
 		}
-		if len(args) > 1 {
+		if len(paths) > 1 {
 			return fmt.Errorf("Too many arguments. Usage: %s", rmUsage)
 		}
-		// TODO not yet implemented
-		fmt.Println("Not yet implemented!")
-		return nil
+
+		if err := removeObjects(project, paths[0], force); err != nil {
+			return err
+		}
+		return project.Save()
 	},
 }
+
+// Removes the objects the path points to. Server groups which are still referenced by servers
+// are only removed together with these servers if force is true. The console and domain user
+// are never removed. Nothing is removed if one of the checks fails.
+func removeObjects(project *model.Project, p string, force bool) error {
+	pth, err := path.Parse(p)
+	if err != nil {
+		return err
+	}
+	targets, err := path.CurrentPath.Append(pth).ResolveAll(project)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("The path does not match any object.")
+	}
+	for _, target := range targets {
+		for _, mandatory := range mandatoryUsers {
+			if strings.HasPrefix(target.Path.String(), mandatory) {
+				return fmt.Errorf(`Unable to remove "%s": The console and domain user are mandatory.`, target)
+			}
+		}
+	}
+
+	dependents, err := dependentServers(project, targets)
+	if err != nil {
+		return err
+	}
+	if len(dependents) != 0 && !force {
+		var buffer bytes.Buffer
+		buffer.WriteString("Unable to remove server groups which are referenced by these servers:\n")
+		for _, dependent := range dependents {
+			buffer.WriteString(fmt.Sprintf("\n    %s", dependent))
+		}
+		buffer.WriteString(fmt.Sprintf("\n\nUse \"rm %s %s\" to remove the servers as well.", forceOption, p))
+		return fmt.Errorf("%s", buffer.String())
+	}
+
+	// check both targets and dependents before removing anything
+	for _, target := range append(dependents, targets...) {
+		if !target.IsRemovable() {
+			return fmt.Errorf(`Unable to remove "%s": Only elements of a collection and optional objects can be removed.`, target)
+		}
+	}
+	if err := path.Remove(dependents); err != nil {
+		return err
+	}
+	if err := path.Remove(targets); err != nil {
+		return err
+	}
+	for _, target := range append(dependents, targets...) {
+		fmt.Printf("Removed %s\n", target)
+	}
+
+	// the current context might be gone
+	if _, err := path.CurrentPath.Resolve(project); err != nil {
+		internalCd(path.Path{})
+		path.LastPath = nil
+	}
+	return nil
+}
+
+// Returns the servers which reference one of the server groups in targets.
+func dependentServers(project *model.Project, targets []path.Target) ([]path.Target, error) {
+	var serverGroups []string
+	for _, target := range targets {
+		if serverGroup, ok := target.Value.Interface().(model.ServerGroup); ok {
+			serverGroups = append(serverGroups, serverGroup.Name)
+		}
+	}
+	if len(serverGroups) == 0 {
+		return nil, nil
+	}
+
+	allServers, _ := path.Parse("hosts[:].servers[:]")
+	servers, err := allServers.ResolveAll(project)
+	if err != nil {
+		return nil, err
+	}
+	var dependents []path.Target
+	for _, server := range servers {
+		if contains(serverGroups, server.Value.Interface().(model.Server).ServerGroup) {
+			dependents = append(dependents, server)
+		}
+	}
+	return dependents, nil
+}
@@ -1,32 +1,159 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	"reflect"
+	"regexp"
+	"strconv"
 )
 
 var setUsage = "set path value,..."
 
+// start+step as in "0+20"
+var sequenceRegex = regexp.MustCompile(`^(-?\d+)\+(-?\d+)$`)
+
 var set = Command{
 	"set",
 	"Modifies an object / attribute of the project model.",
 	setUsage,
-	"Modifies an object / attribute of the project model. TODO: Describe path for set.",
+	`Modifies an object / attribute of the project model. The path is resolved
+relative to the current context:
+
+    set hosts[master].servers[0].port-offset 150
+
+The value is converted to the type of the attribute: Strings can be given as
+is or enclosed in double quotes, numbers and booleans are parsed and objects
+are given as JSON:
+
+    set hosts[master].servers[0].jvm {"name":"s0jvm","heap":{"initial":"1GB","max":"2GB"}}
+
+Use "null" to remove an optional object like a JVM.
+
+If the path contains ranges, globs, filters or descents, you can specify one
+value per object. The values are separated with "," and assigned in the order
+of the objects:
+
+    set hosts[master].servers[:].auto-start true,false,false,true
+    set **.port-offset 0
+
+A single value is assigned to all objects. Use "start+step" to assign an
+arithmetic sequence to numeric attributes:
+
+    set hosts[master].servers[:].port-offset 0+20
+
+Either all or none of the objects are modified.`,
 	// tab completer
-	func(_ *model.Project, _, _ string) ([]string, int) {
-		// TODO not yet implemented
-		return nil, 0
+	func(project *model.Project, query, cmdline string) ([]string, int) {
+		tokens := Tokens(cmdline)
+		if len(tokens) > 2 || (len(tokens) == 2 && query == "") {
+			// the path is complete, no completion for values
+			return nil, 0
+		}
+		return completion(project, query, cmdline, []reflect.Kind{})
 	},
 	// action
-	func(_ *model.Project, args []string) error {
-		if len(args) == 0 {
+	func(project *model.Project, args []string) error {
+		if len(args) < 2 {
 			return fmt.Errorf("Missing arguments. Usage: %s", setUsage)
 		}
 		if len(args) > 2 {
 			return fmt.Errorf("Too many arguments. Usage: %s", setUsage)
 		}
-		// TODO not yet implemented
-		fmt.Println("Not yet implemented!")
-		return nil
+
+		pth, err := path.Parse(args[0])
+		if err != nil {
+			return err
+		}
+		full := path.CurrentPath.Append(pth)
+		targets, err := full.ResolveAll(project)
+		if err != nil {
+			return err
+		}
+		values, err := distribute(splitValues(args[1]), targets)
+		if err != nil {
+			return err
+		}
+		if err := path.SetAll(targets, values); err != nil {
+			return err
+		}
+		return project.Save()
 	},
 }
+
+// Splits the value at commas which are not part of a JSON object, array or string.
+func splitValues(value string) []string {
+	var values []string
+	var depth, start int
+	var quoted, escaped bool
+
+	for i, c := range value {
+		if escaped {
+			escaped = false
+			continue
+		}
+		switch {
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			values = append(values, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(values, value[start:])
+}
+
+// Maps the values onto the targets: A single value is assigned to all targets, a single
+// sequence "start+step" is expanded to one value per target if all targets are integers.
+// Otherwise the number of values must match the number of targets.
+func distribute(values []string, targets []path.Target) ([]string, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("The path does not match any object.")
+	}
+	if len(values) == 1 {
+		distributed := make([]string, len(targets))
+		if groups := sequenceRegex.FindStringSubmatch(values[0]); groups != nil && integers(targets) {
+			start, _ := strconv.Atoi(groups[1])
+			step, _ := strconv.Atoi(groups[2])
+			for i := range distributed {
+				distributed[i] = strconv.Itoa(start + i*step)
+			}
+		} else {
+			for i := range distributed {
+				distributed[i] = values[0]
+			}
+		}
+		return distributed, nil
+	}
+	if len(values) != len(targets) {
+		var buffer bytes.Buffer
+		buffer.WriteString(fmt.Sprintf("%d values given, but the path matches %d objects:\n", len(values), len(targets)))
+		for _, target := range targets {
+			buffer.WriteString(fmt.Sprintf("\n    %s", target))
+		}
+		return nil, fmt.Errorf("%s", buffer.String())
+	}
+	return values, nil
+}
+
+// Returns true if all targets are integer attributes.
+func integers(targets []path.Target) bool {
+	for _, target := range targets {
+		switch target.Value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return false
+		}
+	}
+	return true
+}
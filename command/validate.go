@@ -3,26 +3,63 @@ package command
 import (
 	"fmt"
 	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/validation"
+	"strings"
 )
 
-var validateUsage = "validate"
+var jsonOption = "--json"
+var validateUsage = "validate [" + jsonOption + "]"
 
 var validate = Command{
 	"validate",
 	"Checks whether the project model is valid.",
 	validateUsage,
-	"Checks whether the project model is valid.",
+	`Checks whether the project model is valid. Each finding is reported with its
+severity (error, warning or info), the path of the affected object and a
+message. These rules are checked:
+
+` + validationRules() + `
+
+Use ` + jsonOption + ` to print the findings as JSON.`,
 	// tab completer
-	func(_ *model.Project, _, _ string) ([]string, int) {
+	func(_ *model.Project, query, _ string) ([]string, int) {
+		if strings.HasPrefix(jsonOption, query) {
+			return []string{jsonOption}, ' '
+		}
 		return nil, 0
 	},
 	// action
-	func(_ *model.Project, args []string) error {
-		if len(args) != 0 {
+	func(project *model.Project, args []string) error {
+		if len(args) > 1 || (len(args) == 1 && args[0] != jsonOption) {
 			return fmt.Errorf("Illegal argument. Usage: %s", validateUsage)
 		}
-		// TODO not yet implemented
-		fmt.Println("Not yet implemented!")
+
+		findings := validation.Validate(project)
+		if len(args) == 1 {
+			data, err := validation.Report(findings)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s\n", string(data))
+			return nil
+		}
+
+		for _, finding := range findings {
+			fmt.Println(finding)
+		}
+		if len(findings) != 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%d error(s), %d warning(s), %d info(s)\n", validation.Count(findings, validation.Error),
+			validation.Count(findings, validation.Warning), validation.Count(findings, validation.Info))
 		return nil
 	},
 }
+
+func validationRules() string {
+	var descriptions []string
+	for _, rule := range validation.Rules() {
+		descriptions = append(descriptions, fmt.Sprintf("    - %s: %s", rule.Name, rule.Description))
+	}
+	return strings.Join(descriptions, "\n")
+}
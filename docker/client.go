@@ -0,0 +1,221 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A minimal client for the Docker Remote API. The endpoint is either a unix socket like
+// "unix:///var/run/docker.sock" or a TCP endpoint like "tcp://localhost:2375".
+type Client struct {
+	endpoint string
+	base     string
+	http     *http.Client
+}
+
+func NewClient(endpoint string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf(`Invalid Docker endpoint "%s": %s`, endpoint, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf(`Invalid Docker endpoint "%s": Missing socket.`, endpoint)
+		}
+		socket := u.Path
+		transport := &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", socket)
+			},
+		}
+		// the host is ignored when dialing the socket
+		return &Client{endpoint, "http://docker", &http.Client{Transport: transport}}, nil
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf(`Invalid Docker endpoint "%s": Missing host.`, endpoint)
+		}
+		return &Client{endpoint, "http://" + u.Host, &http.Client{}}, nil
+	default:
+		return nil, fmt.Errorf(`Unsupported Docker endpoint "%s". Please use "unix://<socket>" or "tcp://<host>:<port>".`, endpoint)
+	}
+}
+
+// Returns the host of the Docker daemon. Published ports are reachable on this host. For unix
+// sockets this is the local host.
+func (client *Client) Host() string {
+	if u, err := url.Parse(client.endpoint); err == nil && u.Scheme == "tcp" {
+		return u.Hostname()
+	}
+	return "localhost"
+}
+
+func (client *Client) String() string {
+	return client.endpoint
+}
+
+// Builds an image from the given files and tags it. The files are sent as tar archive. The
+// build output is written to progress.
+func (client *Client) Build(tag string, files map[string][]byte, progress io.Writer) error {
+	context, err := tarArchive(files)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"t": {tag}, "rm": {"1"}, "forcerm": {"1"}}
+	header := http.Header{"Content-Type": {"application/x-tar"}}
+	response, err := client.do("POST", "/build", query, header, context)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return readStream(response.Body, progress)
+}
+
+// ------------------------------------------------------ http
+
+// Sends a request to the Docker API. Responses with a status code other than 2xx are turned
+// into an error which includes the message of the daemon.
+func (client *Client) do(method, path string, query url.Values, header http.Header, body io.Reader) (*http.Response, error) {
+	target := client.base + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+	request, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf(`Unable to connect to Docker at "%s": %s`, client.endpoint, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		defer response.Body.Close()
+		message, _ := ioutil.ReadAll(response.Body)
+		return nil, &Error{response.StatusCode, method, path, errorMessage(message)}
+	}
+	return response, nil
+}
+
+// Sends a request with a JSON body (if not nil) and decodes the JSON response into result
+// (if not nil).
+func (client *Client) doJson(method, path string, query url.Values, body interface{}, result interface{}) error {
+	var reader io.Reader
+	var header http.Header
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		header = http.Header{"Content-Type": {"application/json"}}
+	}
+	response, err := client.do(method, path, query, header, reader)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if result != nil {
+		return json.NewDecoder(response.Body).Decode(result)
+	}
+	return nil
+}
+
+// An error reported by the Docker daemon.
+type Error struct {
+	Status  int
+	Method  string
+	Path    string
+	Message string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("Docker error %d on %s %s: %s", err.Status, err.Method, err.Path, err.Message)
+}
+
+// Newer daemons return errors as {"message": "..."}, older ones as plain text.
+func errorMessage(body []byte) string {
+	var message struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &message) == nil && message.Message != "" {
+		return message.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// ------------------------------------------------------ streams
+
+// A message of the JSON stream returned by build, pull and push.
+type streamMessage struct {
+	Stream   string `json:"stream"`
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+	ID       string `json:"id"`
+	Error    string `json:"error"`
+}
+
+// Reads the JSON stream and writes the messages to progress. The first error message in the
+// stream is returned as error.
+func readStream(stream io.Reader, progress io.Writer) error {
+	decoder := json.NewDecoder(stream)
+	for {
+		var message streamMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case message.Error != "":
+			return fmt.Errorf("%s", strings.TrimSpace(message.Error))
+		case message.Stream != "":
+			io.WriteString(progress, message.Stream)
+		case message.Status != "":
+			line := message.Status
+			if message.ID != "" {
+				line = message.ID + ": " + line
+			}
+			if message.Progress != "" {
+				line += " " + message.Progress
+			}
+			fmt.Fprintln(progress, line)
+		}
+	}
+}
+
+// Creates a tar archive of the given files. The files are added in alphabetical order.
+func tarArchive(files map[string][]byte) (io.Reader, error) {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name]))}
+		if err := writer.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return &buffer, nil
+}
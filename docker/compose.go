@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/ports"
+	"github.com/hpehl/whatunga/template"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The file name of the compose file.
+const ComposeFile = "docker-compose.yml"
+
+// Returns a compose file with one service per host. The services are named after the hosts and
+// are built from the build contexts in "./<host>" as written by "generate docker". They use the
+// same image names, container names, labels, network and published ports as "docker start", so
+// the other docker commands work with these containers, too. The network is given an explicit
+// name, otherwise docker-compose would prefix it with its project name. The slaves depend on the
+// domain controller which is considered healthy as soon as its native management port accepts
+// connections.
+func Compose(project *model.Project) ([]byte, error) {
+	dc, _, err := domainController(project)
+	if err != nil {
+		return nil, err
+	}
+	domain, err := template.ReadDomain(project.Config.Templates.Domain)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := ports.Plan(project, domain)
+	if err != nil {
+		return nil, err
+	}
+	network := NetworkName(project)
+
+	var buffer bytes.Buffer
+	// version 2.4 supports both named networks and health conditions
+	buffer.WriteString("version: \"2.4\"\n\nservices:\n")
+	for _, host := range project.Hosts {
+		fmt.Fprintf(&buffer, "  %s:\n", repositoryName(host.Name))
+		fmt.Fprintf(&buffer, "    build: %s\n", quote("./"+host.Name))
+		fmt.Fprintf(&buffer, "    image: %s\n", quote(ImageName(project, host)))
+		fmt.Fprintf(&buffer, "    container_name: %s\n", quote(ContainerName(project, host)))
+		fmt.Fprintf(&buffer, "    hostname: %s\n", quote(host.Name))
+		buffer.WriteString("    labels:\n")
+		labels := Labels(project, host)
+		for _, key := range sortedKeys(labels) {
+			fmt.Fprintf(&buffer, "      %s: %s\n", key, quote(labels[key]))
+		}
+		fmt.Fprintf(&buffer, "    networks:\n      %s:\n        aliases:\n          - %s\n", network, quote(host.Name))
+		if _, bindings := publish(project, host, plan); len(bindings) != 0 {
+			buffer.WriteString("    ports:\n")
+			for _, port := range sortedPorts(bindings) {
+				mapping := port
+				if hostPort := bindings[port+"/tcp"][0].HostPort; hostPort != "" {
+					mapping = hostPort + ":" + port
+				}
+				fmt.Fprintf(&buffer, "      - %s\n", quote(mapping))
+			}
+		}
+		fmt.Fprintf(&buffer, "    healthcheck:\n      test: [\"CMD-SHELL\", %s]\n", quote(
+			fmt.Sprintf("bash -c 'echo > /dev/tcp/localhost/%d'", nativePort(plan, host))))
+		buffer.WriteString("      interval: 5s\n      timeout: 3s\n      retries: 24\n")
+		if !host.DC {
+			fmt.Fprintf(&buffer, "    depends_on:\n      %s:\n        condition: service_healthy\n", repositoryName(dc.Name))
+		}
+	}
+	fmt.Fprintf(&buffer, "\nnetworks:\n  %s:\n    name: %s\n    driver: bridge\n", network, quote(network))
+	fmt.Fprintf(&buffer, "    labels:\n      %s: %s\n", ProjectLabel, quote(project.Name))
+	return buffer.Bytes(), nil
+}
+
+// Returns the native management port of the host controller.
+func nativePort(plan []ports.Port, host model.Host) int {
+	for _, port := range plan {
+		if port.Host == host.Name && port.Server == "" && port.Binding == ports.ManagementNative {
+			return port.Port
+		}
+	}
+	return managementPort
+}
+
+// Returns the container ports of the bindings in ascending order.
+func sortedPorts(bindings map[string][]PortBinding) []string {
+	var numbers []int
+	for key := range bindings {
+		number, _ := strconv.Atoi(strings.TrimSuffix(key, "/tcp"))
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	var sorted []string
+	for _, number := range numbers {
+		sorted = append(sorted, strconv.Itoa(number))
+	}
+	return sorted
+}
+
+func sortedKeys(values map[string]string) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Double quoted YAML strings use the same escapes as Go.
+func quote(value string) string {
+	return strconv.Quote(value)
+}
@@ -0,0 +1,224 @@
+package docker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// The configuration used to create a container.
+type ContainerConfig struct {
+	Image            string              `json:"Image"`
+	Hostname         string              `json:"Hostname,omitempty"`
+	Cmd              []string            `json:"Cmd,omitempty"`
+	Labels           map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts     map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig       *HostConfig         `json:"HostConfig,omitempty"`
+	NetworkingConfig *NetworkingConfig   `json:"NetworkingConfig,omitempty"`
+}
+
+type HostConfig struct {
+	NetworkMode  string                   `json:"NetworkMode,omitempty"`
+	PortBindings map[string][]PortBinding `json:"PortBindings,omitempty"`
+	Binds        []string                 `json:"Binds,omitempty"`
+}
+
+// Publishes a container port on the Docker host. An empty host port publishes the container
+// port on a random port.
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// Connects the container to networks when it's created.
+type NetworkingConfig struct {
+	EndpointsConfig map[string]EndpointSettings `json:"EndpointsConfig"`
+}
+
+type EndpointSettings struct {
+	Aliases   []string `json:"Aliases,omitempty"`
+	IPAddress string   `json:"IPAddress,omitempty"`
+}
+
+// The details of a container as returned by inspect.
+type Container struct {
+	ID              string          `json:"Id"`
+	Name            string          `json:"Name"`
+	State           ContainerState  `json:"State"`
+	NetworkSettings NetworkSettings `json:"NetworkSettings"`
+}
+
+type ContainerState struct {
+	Running  bool `json:"Running"`
+	ExitCode int  `json:"ExitCode"`
+}
+
+type NetworkSettings struct {
+	IPAddress string                      `json:"IPAddress"`
+	Networks  map[string]EndpointSettings `json:"Networks"`
+	Ports     map[string][]PortBinding    `json:"Ports"`
+}
+
+// Returns the address of the container in the given network. Falls back to the address in the
+// default network.
+func (settings NetworkSettings) Address(network string) string {
+	if endpoint, ok := settings.Networks[network]; ok && endpoint.IPAddress != "" {
+		return endpoint.IPAddress
+	}
+	return settings.IPAddress
+}
+
+// Returns the port on the Docker host the given TCP port of the container is published on or ""
+// if the port is not published.
+func (settings NetworkSettings) PublishedPort(port int) string {
+	for _, binding := range settings.Ports[strconv.Itoa(port)+"/tcp"] {
+		if binding.HostPort != "" {
+			return binding.HostPort
+		}
+	}
+	return ""
+}
+
+// A container as returned by the list operation.
+type ContainerSummary struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Ports  []Port            `json:"Ports"`
+	Labels map[string]string `json:"Labels"`
+}
+
+type Port struct {
+	IP          string `json:"IP"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
+func (port Port) String() string {
+	if port.PublicPort == 0 {
+		return fmt.Sprintf("%d/%s", port.PrivatePort, port.Type)
+	}
+	return fmt.Sprintf("%s:%d->%d/%s", port.IP, port.PublicPort, port.PrivatePort, port.Type)
+}
+
+// Creates a container with the given name and returns its ID.
+func (client *Client) CreateContainer(name string, config ContainerConfig) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	query := url.Values{"name": {name}}
+	if err := client.doJson("POST", "/containers/create", query, config, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (client *Client) StartContainer(id string) error {
+	return client.doJson("POST", "/containers/"+id+"/start", nil, nil, nil)
+}
+
+func (client *Client) InspectContainer(id string) (*Container, error) {
+	var container Container
+	if err := client.doJson("GET", "/containers/"+id+"/json", nil, nil, &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// Removes the container including its volumes. Running containers are killed first.
+func (client *Client) RemoveContainer(id string) error {
+	query := url.Values{"force": {"1"}, "v": {"1"}}
+	return client.doJson("DELETE", "/containers/"+id, query, nil, nil)
+}
+
+// Returns all containers (including stopped ones) which have all of the given labels.
+func (client *Client) ListContainers(labels map[string]string) ([]ContainerSummary, error) {
+	query := url.Values{"all": {"1"}}
+	if len(labels) != 0 {
+		var filter []string
+		for name, value := range labels {
+			filter = append(filter, name+"="+value)
+		}
+		filters, err := json.Marshal(map[string][]string{"label": filter})
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(filters))
+	}
+	var containers []ContainerSummary
+	if err := client.doJson("GET", "/containers/json", query, nil, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// Stops the container. The container is killed if it's still running after timeout seconds.
+// Stopping a container which is not running is not an error.
+func (client *Client) StopContainer(id string, timeout int) error {
+	query := url.Values{"t": {strconv.Itoa(timeout)}}
+	err := client.doJson("POST", "/containers/"+id+"/stop", query, nil, nil)
+	if dockerErr, ok := err.(*Error); ok && dockerErr.Status == http.StatusNotModified {
+		return nil
+	}
+	return err
+}
+
+// Copies the log of the container to stdout and stderr. If follow is true, the log is streamed
+// until the container stops or cancel is closed.
+func (client *Client) ContainerLogs(id string, follow bool, stdout, stderr io.Writer, cancel <-chan struct{}) error {
+	query := url.Values{"stdout": {"1"}, "stderr": {"1"}}
+	if follow {
+		query.Set("follow", "1")
+	}
+	response, err := client.do("GET", "/containers/"+id+"/logs", query, nil, nil)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-cancel:
+			response.Body.Close()
+		case <-done:
+			response.Body.Close()
+		}
+	}()
+
+	err = demultiplex(response.Body, stdout, stderr)
+	select {
+	case <-cancel:
+		return nil // reading from the closed body fails
+	default:
+		return err
+	}
+}
+
+// Splits the multiplexed stream of a container without TTY into stdout and stderr. Each frame
+// starts with an eight byte header: The stream type (1 = stdout, 2 = stderr), three bytes
+// padding and the size of the payload as big endian uint32.
+func demultiplex(stream io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(stream, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		out := stdout
+		if header[0] == 2 {
+			out = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(out, stream, size); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"bytes"
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+	"path/filepath"
+)
+
+// ------------------------------------------------------ setup
+
+type ClientSuite struct {
+	fake   *fakeDocker
+	client *Client
+}
+
+func (s *ClientSuite) SetUpTest(c *C) {
+	s.fake = newFakeDocker()
+	client, err := NewClient(s.fake.endpoint())
+	c.Assert(err, IsNil)
+	s.client = client
+}
+
+func (s *ClientSuite) TearDownTest(c *C) {
+	s.fake.close()
+}
+
+var _ = Suite(&ClientSuite{})
+
+// ------------------------------------------------------ client tests
+
+func (s *ClientSuite) TestBuild(c *C) {
+	var progress bytes.Buffer
+	files := map[string][]byte{
+		"Dockerfile": []byte("FROM jboss/wildfly:8.1.0.Final\nADD host.xml /opt/jboss/wildfly/domain/configuration/host.xml\n"),
+		"host.xml":   []byte("<host/>"),
+	}
+	err := s.client.Build("test/master:1.0", files, &progress)
+	c.Assert(err, IsNil)
+	c.Assert(s.fake.images["test/master:1.0"], DeepEquals, files)
+	c.Assert(progress.String(), Equals, `Step 0 : FROM jboss/wildfly:8.1.0.Final
+Step 1 : ADD host.xml /opt/jboss/wildfly/domain/configuration/host.xml
+Successfully built 4711
+`)
+}
+
+func (s *ClientSuite) TestBuildUnixSocket(c *C) {
+	fake, err := newFakeDockerSocket(filepath.Join(c.MkDir(), "docker.sock"))
+	c.Assert(err, IsNil)
+	defer fake.close()
+	client, err := NewClient(fake.endpoint())
+	c.Assert(err, IsNil)
+
+	var progress bytes.Buffer
+	err = client.Build("test/master:1.0", map[string][]byte{"Dockerfile": []byte("FROM scratch")}, &progress)
+	c.Assert(err, IsNil)
+	c.Assert(fake.images, HasLen, 1)
+}
+
+func (s *ClientSuite) TestImageName(c *C) {
+	project := &model.Project{Name: "My Project", Version: "1.0-SNAPSHOT"}
+	c.Assert(ImageName(project, model.Host{Name: "master"}), Equals, "my-project/master:1.0-SNAPSHOT")
+	c.Assert(ImageName(project, model.Host{Name: "Slave_1"}), Equals, "my-project/slave_1:1.0-SNAPSHOT")
+	c.Assert(ImageName(&model.Project{Name: "foo"}, model.Host{Name: "bar"}), Equals, "foo/bar:latest")
+}
+
+func (s *ClientSuite) TestContainerAndNetworkName(c *C) {
+	project := &model.Project{Name: "My Project", Version: "1.0"}
+	c.Assert(NetworkName(project), Equals, "my-project")
+	c.Assert(ContainerName(project, model.Host{Name: "Slave_1"}), Equals, "my-project-slave_1")
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *ClientSuite) TestHost(c *C) {
+	client, err := NewClient("tcp://docker.example.com:2375")
+	c.Assert(err, IsNil)
+	c.Assert(client.Host(), Equals, "docker.example.com")
+	client, err = NewClient("unix:///var/run/docker.sock")
+	c.Assert(err, IsNil)
+	c.Assert(client.Host(), Equals, "localhost")
+}
+
+func (s *ClientSuite) TestBuildError(c *C) {
+	var progress bytes.Buffer
+	err := s.client.Build("test/master:1.0", map[string][]byte{"host.xml": []byte("<host/>")}, &progress)
+	c.Assert(err, ErrorMatches, "Cannot locate specified Dockerfile: Dockerfile")
+}
+
+func (s *ClientSuite) TestApiError(c *C) {
+	client, err := NewClient(s.fake.endpoint())
+	c.Assert(err, IsNil)
+	_, err = client.do("GET", "/unknown", nil, nil, nil)
+	c.Assert(err, ErrorMatches, "Docker error 404 on GET /unknown: 404 page not found")
+}
+
+func (s *ClientSuite) TestUnreachable(c *C) {
+	client, err := NewClient("unix://" + filepath.Join(c.MkDir(), "missing.sock"))
+	c.Assert(err, IsNil)
+	err = client.Build("test/master:1.0", map[string][]byte{}, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, `Unable to connect to Docker at "unix://.*/missing.sock": .*`)
+}
+
+func (s *ClientSuite) TestInvalidEndpoints(c *C) {
+	_, err := NewClient("http://localhost:2375")
+	c.Assert(err, ErrorMatches, `Unsupported Docker endpoint "http://localhost:2375". Please use "unix://<socket>" or "tcp://<host>:<port>".`)
+	_, err = NewClient("unix://")
+	c.Assert(err, ErrorMatches, `Invalid Docker endpoint "unix://": Missing socket.`)
+	_, err = NewClient("tcp://")
+	c.Assert(err, ErrorMatches, `Invalid Docker endpoint "tcp://": Missing host.`)
+}
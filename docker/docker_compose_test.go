@@ -0,0 +1,89 @@
+package docker
+
+import (
+	. "gopkg.in/check.v1"
+	"strings"
+)
+
+// ------------------------------------------------------ compose tests
+
+// uses the project of StartSuite
+func (s *StartSuite) TestCompose(c *C) {
+	s.project.Hosts = s.project.Hosts[:2]
+	data, err := Compose(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `version: "2.4"
+
+services:
+  slave1:
+    build: "./slave1"
+    image: "test/slave1:1.0"
+    container_name: "test-slave1"
+    hostname: "slave1"
+    labels:
+      org.whatunga.host: "slave1"
+      org.whatunga.project: "test"
+      org.whatunga.version: "1.0"
+    networks:
+      test:
+        aliases:
+          - "slave1"
+    ports:
+      - "8080:8080"
+      - "8180:8180"
+      - "8443:8443"
+      - "8543:8543"
+    healthcheck:
+      test: ["CMD-SHELL", "bash -c 'echo > /dev/tcp/localhost/9999'"]
+      interval: 5s
+      timeout: 3s
+      retries: 24
+    depends_on:
+      master:
+        condition: service_healthy
+  master:
+    build: "./master"
+    image: "test/master:1.0"
+    container_name: "test-master"
+    hostname: "master"
+    labels:
+      org.whatunga.host: "master"
+      org.whatunga.project: "test"
+      org.whatunga.version: "1.0"
+    networks:
+      test:
+        aliases:
+          - "master"
+    ports:
+      - "9990:9990"
+      - "9999:9999"
+    healthcheck:
+      test: ["CMD-SHELL", "bash -c 'echo > /dev/tcp/localhost/9999'"]
+      interval: 5s
+      timeout: 3s
+      retries: 24
+
+networks:
+  test:
+    name: "test"
+    driver: bridge
+    labels:
+      org.whatunga.project: "test"
+`)
+}
+
+func (s *StartSuite) TestComposeRandomPorts(c *C) {
+	data, err := Compose(s.project)
+	c.Assert(err, IsNil)
+	// both slaves use 8080 and 8443
+	c.Assert(strings.Count(string(data), `- "8080"`), Equals, 2)
+	c.Assert(strings.Count(string(data), `- "8180:8180"`), Equals, 1)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *StartSuite) TestComposeWithoutDomainController(c *C) {
+	s.project.Hosts[1].DC = false
+	_, err := Compose(s.project)
+	c.Assert(err, ErrorMatches, "Exactly one host must be the domain controller, but found 0.")
+}
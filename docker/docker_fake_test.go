@@ -0,0 +1,444 @@
+package docker
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An in-process stand-in for the Docker Remote API. It implements just enough of the API to
+// test the client.
+type fakeDocker struct {
+	sync.Mutex
+	server *httptest.Server
+	// the build contexts keyed by image name
+	images map[string]map[string][]byte
+	// the containers keyed by ID and the IDs in order of creation
+	containers map[string]*fakeContainer
+	created    []string
+	// starting a container of these images fails with the given message
+	failStart map[string]string
+	// containers of these images exit right after they've been started
+	exitOnStart map[string]bool
+	// started containers get no IP address, as if they're not reachable from the client
+	noAddress bool
+	// the host ports of published ports keyed by "<port>/tcp". Ports which are published on
+	// random host ports get increasing ports starting at 32768 otherwise.
+	hostPorts   map[string]string
+	randomPorts int
+	// the stdout and stderr logs keyed by image
+	stdout map[string]string
+	stderr map[string]string
+	// the tagged images keyed by "repository:tag"
+	tags map[string]string
+	// the pushed images and the decoded X-Registry-Auth headers
+	pushed []string
+	auths  []AuthConfig
+	// the number of pushes which fail before pushes succeed
+	failPushes int
+	// the networks keyed by ID
+	networks map[string]*Network
+}
+
+type fakeContainer struct {
+	Container
+	config  ContainerConfig
+	removed bool
+}
+
+// Starts a fake Docker API listening on TCP.
+func newFakeDocker() *fakeDocker {
+	fake := newFake()
+	fake.server = httptest.NewServer(fake.handler())
+	return fake
+}
+
+// Starts a fake Docker API listening on the given unix socket.
+func newFakeDockerSocket(socket string) (*fakeDocker, error) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	fake := newFake()
+	fake.server = httptest.NewUnstartedServer(fake.handler())
+	fake.server.Listener.Close()
+	fake.server.Listener = listener
+	fake.server.Start()
+	return fake, nil
+}
+
+func newFake() *fakeDocker {
+	return &fakeDocker{
+		images:      make(map[string]map[string][]byte),
+		containers:  make(map[string]*fakeContainer),
+		failStart:   make(map[string]string),
+		exitOnStart: make(map[string]bool),
+		hostPorts:   make(map[string]string),
+		stdout:      make(map[string]string),
+		stderr:      make(map[string]string),
+		tags:        make(map[string]string),
+		networks:    make(map[string]*Network),
+	}
+}
+
+// Returns the endpoint as used in config.docker-remote-api.
+func (fake *fakeDocker) endpoint() string {
+	if fake.server.Listener.Addr().Network() == "unix" {
+		return "unix://" + fake.server.Listener.Addr().String()
+	}
+	return "tcp://" + fake.server.Listener.Addr().String()
+}
+
+func (fake *fakeDocker) close() {
+	fake.server.Close()
+}
+
+func (fake *fakeDocker) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/build", fake.build)
+	mux.HandleFunc("/containers/create", fake.createContainer)
+	mux.HandleFunc("/containers/json", fake.listContainers)
+	mux.HandleFunc("/containers/", fake.container)
+	mux.HandleFunc("/images/", fake.image)
+	mux.HandleFunc("/networks", fake.listNetworks)
+	mux.HandleFunc("/networks/create", fake.createNetwork)
+	mux.HandleFunc("/networks/", fake.removeNetwork)
+	return mux
+}
+
+func (fake *fakeDocker) build(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.Header.Get("Content-Type") != "application/x-tar" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	files := make(map[string][]byte)
+	reader := tar.NewReader(r.Body)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		files[header.Name], _ = ioutil.ReadAll(reader)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	dockerfile, ok := files["Dockerfile"]
+	if !ok {
+		encoder.Encode(map[string]string{"error": "Cannot locate specified Dockerfile: Dockerfile"})
+		return
+	}
+	for i, line := range strings.Split(strings.TrimSpace(string(dockerfile)), "\n") {
+		encoder.Encode(map[string]string{"stream": fmt.Sprintf("Step %d : %s\n", i, line)})
+	}
+	encoder.Encode(map[string]string{"stream": "Successfully built 4711\n"})
+
+	fake.Lock()
+	defer fake.Unlock()
+	fake.images[r.URL.Query().Get("t")] = files
+}
+
+func (fake *fakeDocker) createContainer(w http.ResponseWriter, r *http.Request) {
+	var config ContainerConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fake.Lock()
+	defer fake.Unlock()
+	name := r.URL.Query().Get("name")
+	for _, container := range fake.containers {
+		if name != "" && container.Name == "/"+name && !container.removed {
+			http.Error(w, `{"message": "Conflict. The name \"`+name+`\" is already in use."}`, http.StatusConflict)
+			return
+		}
+	}
+	if config.HostConfig != nil && config.HostConfig.NetworkMode != "" && fake.network(config.HostConfig.NetworkMode) == nil {
+		http.Error(w, `{"message": "network `+config.HostConfig.NetworkMode+` not found"}`, http.StatusNotFound)
+		return
+	}
+	id := fmt.Sprintf("%064x", len(fake.created)+1)
+	fake.add(id, config)
+	fake.containers[id].Name = "/" + name
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"Id": id})
+}
+
+// Adds a stopped container, e.g. to simulate containers of other projects.
+func (fake *fakeDocker) add(id string, config ContainerConfig) {
+	fake.containers[id] = &fakeContainer{Container: Container{ID: id}, config: config}
+	fake.created = append(fake.created, id)
+}
+
+// Supports the "label" filter only.
+func (fake *fakeDocker) listContainers(w http.ResponseWriter, r *http.Request) {
+	var filters map[string][]string
+	if query := r.URL.Query().Get("filters"); query != "" {
+		if err := json.Unmarshal([]byte(query), &filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	fake.Lock()
+	defer fake.Unlock()
+	containers := []ContainerSummary{}
+	for _, id := range fake.created {
+		container := fake.containers[id]
+		matches := !container.removed
+		for _, label := range filters["label"] {
+			parts := strings.SplitN(label, "=", 2)
+			if value, ok := container.config.Labels[parts[0]]; !ok || value != parts[1] {
+				matches = false
+			}
+		}
+		if matches {
+			state := "exited"
+			if container.State.Running {
+				state = "running"
+			}
+			containers = append(containers, ContainerSummary{
+				ID:     id,
+				Image:  container.config.Image,
+				State:  state,
+				Labels: container.config.Labels,
+				Ports:  []Port{{IP: "0.0.0.0", PrivatePort: 9990, PublicPort: 9990, Type: "tcp"}},
+			})
+		}
+	}
+	json.NewEncoder(w).Encode(containers)
+}
+
+// Handles /containers/<id>/start|stop|logs|json and DELETE /containers/<id>
+func (fake *fakeDocker) container(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/containers/"), "/", 2)
+	container, ok := fake.containers[parts[0]]
+	if !ok || container.removed {
+		http.Error(w, `{"message": "No such container: `+parts[0]+`"}`, http.StatusNotFound)
+		return
+	}
+	var action string
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case r.Method == "POST" && action == "start":
+		if message, ok := fake.failStart[container.config.Image]; ok {
+			http.Error(w, `{"message": "`+message+`"}`, http.StatusInternalServerError)
+			return
+		}
+		container.State.Running = !fake.exitOnStart[container.config.Image]
+		if container.config.HostConfig != nil {
+			container.NetworkSettings.Ports = fake.publish(container.config.HostConfig.PortBindings)
+		}
+		address := "127.0.0.1"
+		if fake.noAddress {
+			address = ""
+		}
+		if container.config.HostConfig != nil && container.config.HostConfig.NetworkMode != "" {
+			// user defined networks don't set the address of the default network
+			container.NetworkSettings.Networks = map[string]EndpointSettings{
+				container.config.HostConfig.NetworkMode: EndpointSettings{IPAddress: address},
+			}
+		} else {
+			container.NetworkSettings.IPAddress = address
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "POST" && action == "stop":
+		if !container.State.Running {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		container.State.Running = false
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && action == "logs":
+		writeFrame(w, 1, fake.stdout[container.config.Image])
+		writeFrame(w, 2, fake.stderr[container.config.Image])
+	case r.Method == "GET" && action == "json":
+		json.NewEncoder(w).Encode(container.Container)
+	case r.Method == "DELETE" && action == "":
+		container.State.Running = false
+		container.removed = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// Returns the published ports as reported by inspect.
+func (fake *fakeDocker) publish(bindings map[string][]PortBinding) map[string][]PortBinding {
+	published := make(map[string][]PortBinding)
+	for port, binding := range bindings {
+		hostPort := binding[0].HostPort
+		if override, ok := fake.hostPorts[port]; ok {
+			hostPort = override
+		} else if hostPort == "" {
+			hostPort = strconv.Itoa(32768 + fake.randomPorts)
+			fake.randomPorts++
+		}
+		published[port] = []PortBinding{{HostIP: "0.0.0.0", HostPort: hostPort}}
+	}
+	return published
+}
+
+func (fake *fakeDocker) containerNamed(name string) *fakeContainer {
+	fake.Lock()
+	defer fake.Unlock()
+	for _, container := range fake.containers {
+		if container.Name == name && !container.removed {
+			return container
+		}
+	}
+	return nil
+}
+
+// Returns the containers which have not been removed in order of creation.
+func (fake *fakeDocker) liveContainers() []*fakeContainer {
+	fake.Lock()
+	defer fake.Unlock()
+	var containers []*fakeContainer
+	for _, id := range fake.created {
+		if !fake.containers[id].removed {
+			containers = append(containers, fake.containers[id])
+		}
+	}
+	return containers
+}
+
+// Writes a frame of the multiplexed log stream.
+func writeFrame(w io.Writer, stream byte, payload string) {
+	if payload == "" {
+		return
+	}
+	header := []byte{stream, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	w.Write(header)
+	io.WriteString(w, payload)
+}
+
+// Handles /images/<name>/tag and /images/<name>/push. Names can contain slashes.
+func (fake *fakeDocker) image(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/images/")
+	switch {
+	case r.Method == "POST" && strings.HasSuffix(name, "/tag"):
+		name = strings.TrimSuffix(name, "/tag")
+		if _, ok := fake.images[name]; !ok {
+			http.Error(w, `{"message": "No such image: `+name+`"}`, http.StatusNotFound)
+			return
+		}
+		fake.tags[r.URL.Query().Get("repo")+":"+r.URL.Query().Get("tag")] = name
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "POST" && strings.HasSuffix(name, "/push"):
+		image := strings.TrimSuffix(name, "/push") + ":" + r.URL.Query().Get("tag")
+		var auth AuthConfig
+		data, _ := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+		json.Unmarshal(data, &auth)
+		fake.auths = append(fake.auths, auth)
+
+		encoder := json.NewEncoder(w)
+		encoder.Encode(map[string]string{"status": "The push refers to a repository [" + image + "]"})
+		if fake.failPushes > 0 {
+			fake.failPushes--
+			encoder.Encode(map[string]string{"error": "connection reset by peer"})
+			return
+		}
+		encoder.Encode(map[string]string{"status": "Pushing", "id": "4711", "progress": "[====>] 1 MB/2 MB"})
+		encoder.Encode(map[string]string{"status": "Image successfully pushed", "id": "4711"})
+		fake.pushed = append(fake.pushed, image)
+	case r.Method == "GET" && strings.HasSuffix(name, "/json"):
+		// images which haven't been built by the fake have no labels
+		var image Image
+		image.ID = "4711"
+		image.Config.Labels = make(map[string]string)
+		dockerfile := fake.images[strings.TrimSuffix(name, "/json")]["Dockerfile"]
+		for _, line := range strings.Split(string(dockerfile), "\n") {
+			if strings.HasPrefix(line, "LABEL ") {
+				label := strings.SplitN(strings.TrimPrefix(line, "LABEL "), "=", 2)
+				image.Config.Labels[label[0]] = label[1]
+			}
+		}
+		json.NewEncoder(w).Encode(image)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// Returns the network with the given name or nil.
+func (fake *fakeDocker) network(name string) *Network {
+	for _, network := range fake.networks {
+		if network.Name == name {
+			return network
+		}
+	}
+	return nil
+}
+
+// Supports the "name" filter only. Like Docker the filter matches substrings.
+func (fake *fakeDocker) listNetworks(w http.ResponseWriter, r *http.Request) {
+	var filters map[string][]string
+	json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+	fake.Lock()
+	defer fake.Unlock()
+	networks := []Network{}
+	for _, network := range fake.networks {
+		if len(filters["name"]) == 0 || strings.Contains(network.Name, filters["name"][0]) {
+			networks = append(networks, *network)
+		}
+	}
+	json.NewEncoder(w).Encode(networks)
+}
+
+func (fake *fakeDocker) createNetwork(w http.ResponseWriter, r *http.Request) {
+	var network Network
+	if err := json.NewDecoder(r.Body).Decode(&network); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fake.Lock()
+	defer fake.Unlock()
+	if fake.network(network.Name) != nil {
+		http.Error(w, `{"message": "network with name `+network.Name+` already exists"}`, http.StatusConflict)
+		return
+	}
+	network.ID = fmt.Sprintf("n%063x", len(fake.networks)+1)
+	fake.networks[network.ID] = &network
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"Id": network.ID})
+}
+
+// Networks with attached containers can't be removed.
+func (fake *fakeDocker) removeNetwork(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+	id := strings.TrimPrefix(r.URL.Path, "/networks/")
+	network, ok := fake.networks[id]
+	if r.Method != "DELETE" || !ok {
+		http.Error(w, `{"message": "No such network: `+id+`"}`, http.StatusNotFound)
+		return
+	}
+	for _, container := range fake.containers {
+		if !container.removed && container.config.HostConfig != nil && container.config.HostConfig.NetworkMode == network.Name {
+			http.Error(w, `{"message": "network `+network.Name+` has active endpoints"}`, http.StatusForbidden)
+			return
+		}
+	}
+	delete(fake.networks, id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,192 @@
+package docker
+
+import (
+	"bytes"
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ------------------------------------------------------ setup
+
+type LifecycleSuite struct {
+	fake    *fakeDocker
+	client  *Client
+	project *model.Project
+}
+
+func (s *LifecycleSuite) SetUpTest(c *C) {
+	s.fake = newFakeDocker()
+	client, err := NewClient(s.fake.endpoint())
+	c.Assert(err, IsNil)
+	s.client = client
+
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		Hosts:   []model.Host{model.Host{Name: "master", DC: true}, model.Host{Name: "slave"}},
+	}
+	// the containers are created in reverse order to verify the ordering by host
+	s.addContainer("2", "test", "1.0", "slave", true)
+	s.addContainer("1", "test", "1.0", "master", true)
+	// a container started before the version was changed
+	s.addContainer("3", "test", "0.9", "master", true)
+	// containers which must never be touched
+	s.addContainer("4", "other", "1.0", "master", true)
+	s.addContainer("5", "", "", "", true)
+}
+
+func (s *LifecycleSuite) TearDownTest(c *C) {
+	s.fake.close()
+}
+
+var _ = Suite(&LifecycleSuite{})
+
+// ------------------------------------------------------ lifecycle tests
+
+func (s *LifecycleSuite) TestContainers(c *C) {
+	containers, err := Containers(s.client, s.project)
+	c.Assert(err, IsNil)
+	c.Assert(ids(containers), DeepEquals, []string{"1", "3", "2"})
+}
+
+func (s *LifecycleSuite) TestStop(c *C) {
+	var progress bytes.Buffer
+	c.Assert(Stop(s.client, s.project, &progress), IsNil)
+	c.Assert(progress.String(), Equals, "slave: Stopped container 2\nmaster: Stopped container 3\nmaster: Stopped container 1\n")
+	c.Assert(s.running(), DeepEquals, []string{"4", "5"})
+
+	// stopping again is a no-op
+	progress.Reset()
+	c.Assert(Stop(s.client, s.project, &progress), IsNil)
+	c.Assert(progress.String(), Equals, "")
+}
+
+func (s *LifecycleSuite) TestRemove(c *C) {
+	var progress bytes.Buffer
+	c.Assert(Remove(s.client, s.project, &progress), IsNil)
+	c.Assert(progress.String(), Equals, "slave: Removed container 2\nmaster: Removed container 3\nmaster: Removed container 1\n")
+	c.Assert(ids(s.allContainers()), DeepEquals, []string{"4", "5"})
+
+	progress.Reset()
+	c.Assert(Remove(s.client, s.project, &progress), IsNil)
+	c.Assert(progress.String(), Equals, "There are no containers for this project.\n")
+}
+
+func (s *LifecycleSuite) TestRemoveNetwork(c *C) {
+	_, err := s.client.CreateNetwork("test", map[string]string{ProjectLabel: "test"})
+	c.Assert(err, IsNil)
+	var progress bytes.Buffer
+	c.Assert(Remove(s.client, s.project, &progress), IsNil)
+	c.Assert(progress.String(), Matches, "(?s).*\nRemoved network test\n")
+	c.Assert(s.fake.networks, HasLen, 0)
+}
+
+func (s *LifecycleSuite) TestRemoveForeignNetwork(c *C) {
+	// a network with the same name which doesn't belong to the project
+	_, err := s.client.CreateNetwork("test", nil)
+	c.Assert(err, IsNil)
+	c.Assert(Remove(s.client, s.project, &bytes.Buffer{}), IsNil)
+	c.Assert(s.fake.networks, HasLen, 1)
+}
+
+func (s *LifecycleSuite) TestStatus(c *C) {
+	s.fake.containers["2"].State.Running = false
+	var out bytes.Buffer
+	c.Assert(Status(s.client, s.project, &out), IsNil)
+	c.Assert(out.String(), Equals, `HOST    VERSION  CONTAINER  STATE    PORTS
+master  1.0      1          running  0.0.0.0:9990->9990/tcp
+master  0.9      3          running  0.0.0.0:9990->9990/tcp
+slave   1.0      2          exited   0.0.0.0:9990->9990/tcp
+`)
+}
+
+func (s *LifecycleSuite) TestLogs(c *C) {
+	s.fake.stdout["test/master:1.0"] = "started\nrunning\n"
+	s.fake.stderr["test/master:1.0"] = "warning"
+	s.fake.stdout["test/slave:1.0"] = "registered\n"
+
+	var out bytes.Buffer
+	c.Assert(Logs(s.client, s.project, nil, false, &out, nil), IsNil)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	c.Assert(lines, DeepEquals, []string{
+		"master | running",
+		"master | started",
+		"master | warning",
+		"slave | registered",
+	})
+
+	out.Reset()
+	c.Assert(Logs(s.client, s.project, []string{"slave"}, false, &out, nil), IsNil)
+	c.Assert(out.String(), Equals, "slave | registered\n")
+}
+
+func (s *LifecycleSuite) TestPrefixWriter(c *C) {
+	var out bytes.Buffer
+	writer := &prefixWriter{prefix: "host | ", out: &out, mutex: &sync.Mutex{}}
+	writer.Write([]byte("first line\nsecond "))
+	writer.Write([]byte("line\nthird"))
+	c.Assert(out.String(), Equals, "host | first line\nhost | second line\n")
+	writer.Flush()
+	c.Assert(out.String(), Equals, "host | first line\nhost | second line\nhost | third\n")
+}
+
+func (s *LifecycleSuite) TestDemultiplex(c *C) {
+	var stream, stdout, stderr bytes.Buffer
+	writeFrame(&stream, 1, "out1\n")
+	writeFrame(&stream, 2, "err1\n")
+	writeFrame(&stream, 1, "out2\n")
+	c.Assert(demultiplex(&stream, &stdout, &stderr), IsNil)
+	c.Assert(stdout.String(), Equals, "out1\nout2\n")
+	c.Assert(stderr.String(), Equals, "err1\n")
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *LifecycleSuite) TestLogsUnknownHost(c *C) {
+	err := Logs(s.client, s.project, []string{"unknown"}, false, &bytes.Buffer{}, nil)
+	c.Assert(err, ErrorMatches, `Unknown host "unknown".`)
+}
+
+func (s *LifecycleSuite) TestLogsWithoutContainers(c *C) {
+	s.project.Hosts = append(s.project.Hosts, model.Host{Name: "new"})
+	err := Logs(s.client, s.project, []string{"new"}, false, &bytes.Buffer{}, nil)
+	c.Assert(err, ErrorMatches, "There are no containers for the selected hosts.")
+}
+
+// ------------------------------------------------------ helper functions
+
+func (s *LifecycleSuite) addContainer(id, project, version, host string, running bool) {
+	config := ContainerConfig{Image: project + "/" + host + ":" + version}
+	if project != "" {
+		config.Labels = map[string]string{ProjectLabel: project, VersionLabel: version, HostLabel: host}
+	}
+	s.fake.add(id, config)
+	s.fake.containers[id].State.Running = running
+}
+
+func (s *LifecycleSuite) allContainers() []ContainerSummary {
+	containers, _ := s.client.ListContainers(nil)
+	return containers
+}
+
+func (s *LifecycleSuite) running() []string {
+	var running []string
+	for _, container := range s.allContainers() {
+		if container.State == "running" {
+			running = append(running, container.ID)
+		}
+	}
+	return running
+}
+
+func ids(containers []ContainerSummary) []string {
+	var ids []string
+	for _, container := range containers {
+		ids = append(ids, container.ID)
+	}
+	return ids
+}
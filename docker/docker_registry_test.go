@@ -0,0 +1,176 @@
+package docker
+
+import (
+	"bytes"
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ------------------------------------------------------ setup
+
+type RegistrySuite struct {
+	fake    *fakeDocker
+	client  *Client
+	project *model.Project
+}
+
+func (s *RegistrySuite) SetUpTest(c *C) {
+	s.fake = newFakeDocker()
+	client, err := NewClient(s.fake.endpoint())
+	c.Assert(err, IsNil)
+	s.client = client
+	retryDelay = time.Millisecond
+
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		Config: model.Config{
+			Registry: model.Registry{URL: "localhost:5000", Repository: "team"},
+		},
+		Hosts: []model.Host{model.Host{Name: "master", DC: true}, model.Host{Name: "slave"}},
+	}
+	// the images built by "docker create"
+	s.fake.images["test/master:1.0"] = map[string][]byte{}
+	s.fake.images["test/slave:1.0"] = map[string][]byte{}
+}
+
+func (s *RegistrySuite) TearDownTest(c *C) {
+	s.fake.close()
+}
+
+var _ = Suite(&RegistrySuite{})
+
+// ------------------------------------------------------ registry tests
+
+func (s *RegistrySuite) TestPush(c *C) {
+	var progress bytes.Buffer
+	c.Assert(Push(s.client, s.project, &progress), IsNil)
+	c.Assert(s.fake.tags, DeepEquals, map[string]string{
+		"localhost:5000/team/test/master:1.0": "test/master:1.0",
+		"localhost:5000/team/test/slave:1.0":  "test/slave:1.0",
+	})
+	c.Assert(s.fake.pushed, DeepEquals, []string{
+		"localhost:5000/team/test/master:1.0",
+		"localhost:5000/team/test/slave:1.0",
+	})
+	c.Assert(s.fake.auths, DeepEquals, []AuthConfig{{}, {}})
+	c.Assert(progress.String(), Matches, `(?s)master: Pushing localhost:5000/team/test/master:1.0
+The push refers to a repository \[localhost:5000/team/test/master:1.0\]
+4711: Pushing \[====>\] 1 MB/2 MB
+4711: Image successfully pushed
+slave: Pushing localhost:5000/team/test/slave:1.0
+.*`)
+}
+
+func (s *RegistrySuite) TestPushRetry(c *C) {
+	s.fake.failPushes = 2
+	var progress bytes.Buffer
+	c.Assert(Push(s.client, s.project, &progress), IsNil)
+	c.Assert(s.fake.pushed, HasLen, 2)
+	c.Assert(progress.String(), Matches, `(?s).*master: Push failed: connection reset by peer. Retrying in 1ms \(1/3\)
+.*master: Push failed: connection reset by peer. Retrying in 2ms \(2/3\)
+.*`)
+}
+
+func (s *RegistrySuite) TestPushWithCredentials(c *C) {
+	os.Setenv("WHATUNGA_TEST_REGISTRY", "alice:secret")
+	defer os.Unsetenv("WHATUNGA_TEST_REGISTRY")
+	s.project.Config.Registry.Credentials = "env:WHATUNGA_TEST_REGISTRY"
+	c.Assert(Push(s.client, s.project, &bytes.Buffer{}), IsNil)
+	c.Assert(s.fake.auths[0], DeepEquals, AuthConfig{Username: "alice", Password: "secret", ServerAddress: "localhost:5000"})
+}
+
+func (s *RegistrySuite) TestRegistryImage(c *C) {
+	registry := model.Registry{URL: "https://registry.example.com/v2/"}
+	repository, tag := RegistryImage(registry, s.project, s.project.Hosts[1])
+	c.Assert(repository, Equals, "registry.example.com/test/slave")
+	c.Assert(tag, Equals, "1.0")
+
+	registry.Repository = "/acme/whatunga/"
+	repository, _ = RegistryImage(registry, s.project, s.project.Hosts[1])
+	c.Assert(repository, Equals, "registry.example.com/acme/whatunga/test/slave")
+}
+
+func (s *RegistrySuite) TestRegistryAuthDockerConfig(c *C) {
+	config := filepath.Join(c.MkDir(), "config.json")
+	// "alice:secret" and "bob:s3cret"
+	data := `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "YWxpY2U6c2VjcmV0"},
+		"localhost:5000": {"auth": "Ym9iOnMzY3JldA=="}
+	}}`
+	c.Assert(ioutil.WriteFile(config, []byte(data), model.FilePerm), IsNil)
+
+	auth, err := RegistryAuth(model.Registry{URL: "localhost:5000", Credentials: config})
+	c.Assert(err, IsNil)
+	c.Assert(*auth, DeepEquals, AuthConfig{Username: "bob", Password: "s3cret", ServerAddress: "localhost:5000"})
+
+	auth, err = RegistryAuth(model.Registry{URL: "index.docker.io", Credentials: config})
+	c.Assert(err, IsNil)
+	c.Assert(auth.Username, Equals, "alice")
+
+	// no entry for the registry and no config file at all
+	auth, err = RegistryAuth(model.Registry{URL: "localhost:6000", Credentials: config})
+	c.Assert(err, IsNil)
+	c.Assert(auth, IsNil)
+	auth, err = RegistryAuth(model.Registry{URL: "localhost:5000", Credentials: config + ".missing"})
+	c.Assert(err, IsNil)
+	c.Assert(auth, IsNil)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *RegistrySuite) TestPushWithoutRegistry(c *C) {
+	s.project.Config.Registry.URL = ""
+	err := Push(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, `No registry configured. Please use "set config.registry.url <host:port>".`)
+}
+
+func (s *RegistrySuite) TestPushMissingImage(c *C) {
+	delete(s.fake.images, "test/slave:1.0")
+	err := Push(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, "Unable to tag image test/slave:1.0: Docker error 404 on POST /images/test/slave:1.0/tag: No such image: test/slave:1.0")
+}
+
+func (s *RegistrySuite) TestPushGivesUp(c *C) {
+	s.fake.failPushes = 10
+	err := Push(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, "Unable to push localhost:5000/team/test/master:1.0 after 4 attempts: connection reset by peer")
+	c.Assert(s.fake.pushed, HasLen, 0)
+}
+
+func (s *RegistrySuite) TestRegistryAuthErrors(c *C) {
+	_, err := RegistryAuth(model.Registry{URL: "localhost:5000", Credentials: "env:WHATUNGA_TEST_UNDEFINED"})
+	c.Assert(err, ErrorMatches, `Unable to read registry credentials: Environment variable "WHATUNGA_TEST_UNDEFINED" is not set.`)
+
+	os.Setenv("WHATUNGA_TEST_REGISTRY", "alice")
+	defer os.Unsetenv("WHATUNGA_TEST_REGISTRY")
+	_, err = RegistryAuth(model.Registry{URL: "localhost:5000", Credentials: "env:WHATUNGA_TEST_REGISTRY"})
+	c.Assert(err, ErrorMatches, `Unable to read registry credentials from "env:WHATUNGA_TEST_REGISTRY": Expected "username:password".`)
+}
+
+func (s *RegistrySuite) TestRegistryAuthCredentialHelpers(c *C) {
+	config := filepath.Join(c.MkDir(), "config.json")
+	data := `{
+		"auths": {"localhost:5000": {}, "localhost:6000": {"auth": "Ym9iOnMzY3JldA=="}},
+		"credsStore": "desktop",
+		"credHelpers": {"registry.example.com": "ecr-login"}
+	}`
+	c.Assert(ioutil.WriteFile(config, []byte(data), model.FilePerm), IsNil)
+
+	_, err := RegistryAuth(model.Registry{URL: "localhost:5000", Credentials: config})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Unable to read registry credentials from "`+config+`": The credentials of "localhost:5000" are kept in the credential helper "desktop", which is not supported. Please use "env:<variable>" instead.`)
+
+	_, err = RegistryAuth(model.Registry{URL: "https://registry.example.com/v2/", Credentials: config})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Unable to read registry credentials from "`+config+`": The credentials of "registry.example.com" are kept in the credential helper "ecr-login", which is not supported. Please use "env:<variable>" instead.`)
+
+	// inline credentials are still used
+	auth, err := RegistryAuth(model.Registry{URL: "localhost:6000", Credentials: config})
+	c.Assert(err, IsNil)
+	c.Assert(auth.Username, Equals, "bob")
+}
@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"bytes"
+	"github.com/hpehl/whatunga/generate"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/template"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ------------------------------------------------------ setup
+
+type StartSuite struct {
+	fake     *fakeDocker
+	client   *Client
+	project  *model.Project
+	listener net.Listener
+}
+
+func (s *StartSuite) SetUpTest(c *C) {
+	s.fake = newFakeDocker()
+	client, err := NewClient(s.fake.endpoint())
+	c.Assert(err, IsNil)
+	s.client = client
+
+	// the fake containers get the address 127.0.0.1, so the management port of the domain
+	// controller is simulated by a local listener
+	s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	managementPort = s.listener.Addr().(*net.TCPAddr).Port
+	// the HTTP management port is not published unless a test publishes it explicitly
+	managementHttpPort = managementPort
+	startTimeout = time.Second
+	pollInterval = 10 * time.Millisecond
+
+	domain := filepath.Join(c.MkDir(), "domain.xml")
+	data, err := template.Asset("templates/wildfly/8.1/domain.xml")
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(domain, data, model.FilePerm), IsNil)
+
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		Config: model.Config{
+			Templates: model.Templates{Domain: domain},
+		},
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "main", Profile: "default", SocketBinding: "standard-sockets"},
+		},
+		Hosts: []model.Host{
+			model.Host{Name: "slave1", Servers: []model.Server{
+				model.Server{Name: "server0", ServerGroup: "main"},
+				model.Server{Name: "server1", ServerGroup: "main", PortOffset: 100},
+			}},
+			model.Host{Name: "master", DC: true},
+			model.Host{Name: "slave2", Servers: []model.Server{
+				model.Server{Name: "server0", ServerGroup: "main"},
+			}},
+		},
+	}
+}
+
+func (s *StartSuite) TearDownTest(c *C) {
+	s.listener.Close()
+	s.fake.close()
+}
+
+var _ = Suite(&StartSuite{})
+
+// ------------------------------------------------------ start tests
+
+func (s *StartSuite) TestStart(c *C) {
+	var progress bytes.Buffer
+	err := Start(s.client, s.project, &progress)
+	c.Assert(err, IsNil)
+
+	containers := s.fake.liveContainers()
+	c.Assert(containers, HasLen, 3)
+	c.Assert(containers[0].Name, Equals, "/test-master")
+	c.Assert(containers[0].config, DeepEquals, ContainerConfig{
+		Image:        "test/master:1.0",
+		Hostname:     "master",
+		Labels:       map[string]string{ProjectLabel: "test", VersionLabel: "1.0", HostLabel: "master"},
+		ExposedPorts: map[string]struct{}{"9990/tcp": {}, "9999/tcp": {}},
+		HostConfig: &HostConfig{NetworkMode: "test", PortBindings: map[string][]PortBinding{
+			"9990/tcp": {{HostPort: "9990"}},
+			"9999/tcp": {{HostPort: "9999"}},
+		}},
+		NetworkingConfig: &NetworkingConfig{EndpointsConfig: map[string]EndpointSettings{
+			"test": EndpointSettings{Aliases: []string{"master"}},
+		}},
+	})
+	var slaves []string
+	for _, container := range containers[1:] {
+		c.Assert(container.State.Running, Equals, true)
+		c.Assert(container.config.HostConfig.NetworkMode, Equals, "test")
+		slaves = append(slaves, container.Name)
+	}
+	sort.Strings(slaves)
+	c.Assert(slaves, DeepEquals, []string{"/test-slave1", "/test-slave2"})
+	// ports used by both slaves are published on random ports
+	slave1 := s.fake.containerNamed("/test-slave1")
+	c.Assert(slave1.config.HostConfig.PortBindings, DeepEquals, map[string][]PortBinding{
+		"8080/tcp": {{HostPort: ""}},
+		"8443/tcp": {{HostPort: ""}},
+		"8180/tcp": {{HostPort: "8180"}},
+		"8543/tcp": {{HostPort: "8543"}},
+	})
+	c.Assert(s.fake.network("test").Labels, DeepEquals, map[string]string{ProjectLabel: "test"})
+	c.Assert(progress.String(), Matches, `(?s)Created network test
+master: Created container test-master \(000000000000\) from test/master:1.0
+master: Started container 000000000000
+master: Waiting for management port 127.0.0.1:\d+
+master: Domain controller is up
+.*slave1: Started container .*`)
+}
+
+func (s *StartSuite) TestStartMounted(c *C) {
+	folder := c.MkDir()
+	generate.DeploymentsFolder = folder
+	defer func() { generate.DeploymentsFolder = "deployments" }()
+	for _, host := range s.project.Hosts {
+		s.fake.images[ImageName(s.project, host)] = map[string][]byte{
+			"Dockerfile": []byte("FROM jboss/wildfly:8.1.0.Final\n\nLABEL " + generate.MountLabel + "=" + generate.MountPoint + "\n"),
+		}
+	}
+	c.Assert(Start(s.client, s.project, &bytes.Buffer{}), IsNil)
+
+	containers := s.fake.liveContainers()
+	c.Assert(containers, HasLen, 3)
+	for _, container := range containers {
+		c.Assert(container.config.HostConfig.Binds, DeepEquals, []string{folder + ":/opt/jboss/deployments:ro"})
+	}
+}
+
+func (s *StartSuite) TestStartPublishedManagementPort(c *C) {
+	// the container address is not reachable as with remote daemons or Docker Desktop, but
+	// the HTTP management port is published on the Docker host
+	management := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer management.Close()
+	managementHttpPort = 9990
+	s.fake.noAddress = true
+	s.fake.hostPorts["9990/tcp"] = strconv.Itoa(management.Listener.Addr().(*net.TCPAddr).Port)
+
+	var progress bytes.Buffer
+	c.Assert(Start(s.client, s.project, &progress), IsNil)
+	c.Assert(s.fake.liveContainers(), HasLen, 3)
+	c.Assert(progress.String(), Matches, `(?s).*master: Waiting for management port http://127.0.0.1:\d+/management
+master: Domain controller is up
+.*`)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *StartSuite) TestStartTwice(c *C) {
+	c.Assert(Start(s.client, s.project, &bytes.Buffer{}), IsNil)
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, `There are already 3 containers for this project. Use "docker rm" to remove them first.`)
+	c.Assert(s.fake.liveContainers(), HasLen, 3)
+}
+
+func (s *StartSuite) TestStartWithoutDomainController(c *C) {
+	s.project.Hosts[1].DC = false
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, "Exactly one host must be the domain controller, but found 0.")
+	c.Assert(s.fake.created, HasLen, 0)
+}
+
+func (s *StartSuite) TestStartDomainControllerExits(c *C) {
+	s.listener.Close()
+	s.fake.exitOnStart["test/master:1.0"] = true
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, "Unable to start domain controller master: Container 000000000000 stopped with exit code 0.")
+	c.Assert(s.fake.created, HasLen, 1)
+	c.Assert(s.fake.liveContainers(), HasLen, 0)
+	c.Assert(s.fake.networks, HasLen, 0)
+}
+
+func (s *StartSuite) TestStartDomainControllerTimeout(c *C) {
+	s.listener.Close()
+	startTimeout = 50 * time.Millisecond
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, `Unable to start domain controller master: Management port 127.0.0.1:\d+ was not opened within 50ms.`)
+	c.Assert(s.fake.liveContainers(), HasLen, 0)
+}
+
+func (s *StartSuite) TestStartDomainControllerBooting(c *C) {
+	// the userland proxy accepts connections to published ports before the domain controller
+	// listens and closes them right away
+	go func() {
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	managementHttpPort = 9990
+	s.fake.noAddress = true
+	s.fake.hostPorts["9990/tcp"] = strconv.Itoa(s.listener.Addr().(*net.TCPAddr).Port)
+	startTimeout = 50 * time.Millisecond
+
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, `Unable to start domain controller master: Management port http://127.0.0.1:\d+/management was not opened within 50ms.`)
+	c.Assert(s.fake.liveContainers(), HasLen, 0)
+}
+
+func (s *StartSuite) TestStartDomainControllerUnreachable(c *C) {
+	s.fake.noAddress = true
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, "Unable to start domain controller master: Container 000000000000 neither publishes the management port nor has an IP address.")
+	c.Assert(s.fake.liveContainers(), HasLen, 0)
+}
+
+func (s *StartSuite) TestStartSlaveFails(c *C) {
+	s.fake.failStart["test/slave2:1.0"] = "port is already allocated"
+	err := Start(s.client, s.project, &bytes.Buffer{})
+	c.Assert(err, ErrorMatches, `Unable to start all hosts. All containers have been removed again:
+
+    slave2: Docker error 500 on POST /containers/0{63}\d/start: port is already allocated`)
+	c.Assert(s.fake.created, HasLen, 3)
+	c.Assert(s.fake.liveContainers(), HasLen, 0)
+	c.Assert(s.fake.networks, HasLen, 0)
+}
+
+func (s *StartSuite) TestStartExistingNetwork(c *C) {
+	id, err := s.client.CreateNetwork("test", nil)
+	c.Assert(err, IsNil)
+	s.fake.failStart["test/master:1.0"] = "oops"
+	c.Assert(Start(s.client, s.project, &bytes.Buffer{}), NotNil)
+	// networks which have not been created by start are kept
+	c.Assert(s.fake.networks[id], NotNil)
+}
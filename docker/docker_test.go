@@ -0,0 +1,9 @@
+package docker
+
+import (
+	. "gopkg.in/check.v1"
+	"testing"
+)
+
+// triggers all tests in this package
+func TestDocker(t *testing.T) { TestingT(t) }
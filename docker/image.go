@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"github.com/hpehl/whatunga/model"
+	"regexp"
+	"strings"
+)
+
+type Image struct {
+	ID     string `json:"Id"`
+	Config struct {
+		Labels map[string]string
+	}
+}
+
+var invalidRepositoryChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Returns the name of the image for the given host: "<project>/<host>:<version>". Characters
+// which are not allowed in repository names or tags are replaced with dashes.
+func ImageName(project *model.Project, host model.Host) string {
+	return repositoryName(project.Name) + "/" + repositoryName(host.Name) + ":" + tagName(project.Version)
+}
+
+func repositoryName(name string) string {
+	return strings.Trim(invalidRepositoryChars.ReplaceAllString(strings.ToLower(name), "-"), "-._")
+}
+
+func tagName(version string) string {
+	tag := invalidTagChars.ReplaceAllString(version, "-")
+	if tag == "" {
+		return "latest"
+	}
+	return tag
+}
+
+// Returns the name of the network shared by all containers of the project.
+func NetworkName(project *model.Project) string {
+	return repositoryName(project.Name)
+}
+
+// Returns the name of the container for the given host: "<project>-<host>". Within the project
+// network the container is also reachable using the plain host name.
+func ContainerName(project *model.Project, host model.Host) string {
+	return repositoryName(project.Name) + "-" + repositoryName(host.Name)
+}
+
+func (client *Client) InspectImage(name string) (*Image, error) {
+	var image Image
+	if err := client.doJson("GET", "/images/"+name+"/json", nil, nil, &image); err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
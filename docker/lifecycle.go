@@ -0,0 +1,264 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// The labels used to identify the containers of a project.
+const (
+	ProjectLabel = "org.whatunga.project"
+	VersionLabel = "org.whatunga.version"
+	HostLabel    = "org.whatunga.host"
+)
+
+// How many seconds to wait for a container to stop before it's killed.
+var stopTimeout = 10
+
+// Returns the labels of the container for the given host.
+func Labels(project *model.Project, host model.Host) map[string]string {
+	return map[string]string{
+		ProjectLabel: project.Name,
+		VersionLabel: project.Version,
+		HostLabel:    host.Name,
+	}
+}
+
+// Returns the containers of the project in the order of the hosts. Containers of all versions
+// are returned, so containers started before the version was changed are still found. The
+// project label is checked again on the client side, so containers of other projects are
+// never returned.
+func Containers(client *Client, project *model.Project) ([]ContainerSummary, error) {
+	all, err := client.ListContainers(map[string]string{ProjectLabel: project.Name})
+	if err != nil {
+		return nil, err
+	}
+	var owned []ContainerSummary
+	for _, container := range all {
+		if container.Labels[ProjectLabel] == project.Name {
+			owned = append(owned, container)
+		}
+	}
+
+	// containers of hosts which are no longer part of the project come last
+	var containers []ContainerSummary
+	for _, host := range project.Hosts {
+		for _, container := range owned {
+			if container.Labels[HostLabel] == host.Name {
+				containers = append(containers, container)
+			}
+		}
+	}
+	for _, container := range owned {
+		if !hasHost(project, container.Labels[HostLabel]) {
+			containers = append(containers, container)
+		}
+	}
+	return containers, nil
+}
+
+// Stops all running containers of the project. The slaves are stopped before the domain
+// controller.
+func Stop(client *Client, project *model.Project, progress io.Writer) error {
+	containers, err := Containers(client, project)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		fmt.Fprintln(progress, "There are no containers for this project.")
+		return nil
+	}
+	for i := len(containers) - 1; i >= 0; i-- {
+		container := containers[i]
+		if !isRunning(container) {
+			continue
+		}
+		if err := client.StopContainer(container.ID, stopTimeout); err != nil {
+			return fmt.Errorf("Unable to stop container %s of host %s: %s",
+				shortId(container.ID), container.Labels[HostLabel], err)
+		}
+		fmt.Fprintf(progress, "%s: Stopped container %s\n", container.Labels[HostLabel], shortId(container.ID))
+	}
+	return nil
+}
+
+// Removes all containers of the project including running ones. Afterwards the project network
+// is removed unless it's still in use.
+func Remove(client *Client, project *model.Project, progress io.Writer) error {
+	containers, err := Containers(client, project)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		fmt.Fprintln(progress, "There are no containers for this project.")
+	}
+	for i := len(containers) - 1; i >= 0; i-- {
+		container := containers[i]
+		if err := client.RemoveContainer(container.ID); err != nil {
+			return fmt.Errorf("Unable to remove container %s of host %s: %s",
+				shortId(container.ID), container.Labels[HostLabel], err)
+		}
+		fmt.Fprintf(progress, "%s: Removed container %s\n", container.Labels[HostLabel], shortId(container.ID))
+	}
+
+	network, err := client.FindNetwork(NetworkName(project))
+	if err != nil {
+		return err
+	}
+	if network == nil || network.Labels[ProjectLabel] != project.Name {
+		return nil
+	}
+	if err := client.RemoveNetwork(network.ID); err != nil {
+		fmt.Fprintf(progress, "Network %s has not been removed: %s\n", network.Name, err)
+	} else {
+		fmt.Fprintf(progress, "Removed network %s\n", network.Name)
+	}
+	return nil
+}
+
+// Writes a table with the host, version, container ID, state and published ports of each
+// container.
+func Status(client *Client, project *model.Project, out io.Writer) error {
+	containers, err := Containers(client, project)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		fmt.Fprintln(out, "There are no containers for this project.")
+		return nil
+	}
+	table := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "HOST\tVERSION\tCONTAINER\tSTATE\tPORTS")
+	for _, container := range containers {
+		var ports []string
+		for _, port := range container.Ports {
+			ports = append(ports, port.String())
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", container.Labels[HostLabel], container.Labels[VersionLabel],
+			shortId(container.ID), state(container), strings.Join(ports, ", "))
+	}
+	return table.Flush()
+}
+
+// Writes the logs of the containers of the given hosts (all hosts if none are given). Each
+// line is prefixed with the host name. If follow is true, the logs are streamed until all
+// containers have stopped or cancel is closed.
+func Logs(client *Client, project *model.Project, hosts []string, follow bool, out io.Writer, cancel <-chan struct{}) error {
+	for _, host := range hosts {
+		if !hasHost(project, host) {
+			return fmt.Errorf(`Unknown host "%s".`, host)
+		}
+	}
+	containers, err := Containers(client, project)
+	if err != nil {
+		return err
+	}
+	var selected []ContainerSummary
+	for _, container := range containers {
+		if len(hosts) == 0 || contains(hosts, container.Labels[HostLabel]) {
+			selected = append(selected, container)
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("There are no containers for the selected hosts.")
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	errors := make([]error, len(selected))
+	for i, container := range selected {
+		wg.Add(1)
+		go func(i int, container ContainerSummary) {
+			defer wg.Done()
+			writer := &prefixWriter{prefix: container.Labels[HostLabel] + " | ", out: out, mutex: &mutex}
+			errors[i] = client.ContainerLogs(container.ID, follow, writer, writer, cancel)
+			writer.Flush()
+		}(i, container)
+	}
+	wg.Wait()
+	for _, err := range errors {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isRunning(container ContainerSummary) bool {
+	if container.State != "" {
+		return container.State == "running"
+	}
+	// older daemons don't return the state
+	return strings.HasPrefix(container.Status, "Up")
+}
+
+func state(container ContainerSummary) string {
+	if container.State != "" {
+		return container.State
+	}
+	return container.Status
+}
+
+func hasHost(project *model.Project, name string) bool {
+	for _, host := range project.Hosts {
+		if host.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes complete lines prefixed with a fixed string. Writers sharing the same mutex don't
+// interleave their lines.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mutex  *sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (writer *prefixWriter) Write(data []byte) (int, error) {
+	writer.buffer.Write(data)
+	for {
+		line, err := writer.buffer.ReadBytes('\n')
+		if err != nil {
+			// incomplete line, keep it for the next write
+			writer.buffer.Write(line)
+			return len(data), nil
+		}
+		if err := writer.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Writes the remaining incomplete line if any.
+func (writer *prefixWriter) Flush() error {
+	if writer.buffer.Len() == 0 {
+		return nil
+	}
+	line := append(writer.buffer.Bytes(), '\n')
+	writer.buffer.Reset()
+	return writer.writeLine(line)
+}
+
+func (writer *prefixWriter) writeLine(line []byte) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	_, err := io.WriteString(writer.out, writer.prefix+string(line))
+	return err
+}
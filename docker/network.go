@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+type Network struct {
+	ID     string            `json:"Id"`
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// Returns the network with the given name or nil if there's no such network.
+func (client *Client) FindNetwork(name string) (*Network, error) {
+	filters, err := json.Marshal(map[string][]string{"name": {name}})
+	if err != nil {
+		return nil, err
+	}
+	var networks []Network
+	query := url.Values{"filters": {string(filters)}}
+	if err := client.doJson("GET", "/networks", query, nil, &networks); err != nil {
+		return nil, err
+	}
+	// the name filter matches substrings
+	for _, network := range networks {
+		if network.Name == name {
+			return &network, nil
+		}
+	}
+	return nil, nil
+}
+
+// Creates a bridge network and returns its ID.
+func (client *Client) CreateNetwork(name string, labels map[string]string) (string, error) {
+	request := map[string]interface{}{
+		"Name":           name,
+		"Driver":         "bridge",
+		"CheckDuplicate": true,
+		"Labels":         labels,
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := client.doJson("POST", "/networks/create", nil, request, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (client *Client) RemoveNetwork(id string) error {
+	return client.doJson("DELETE", "/networks/"+id, nil, nil, nil)
+}
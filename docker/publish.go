@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/ports"
+	"strconv"
+)
+
+// Returns the exposed ports and port bindings for the container of the given host. The well
+// known ports of the servers are published and for the domain controller the management ports
+// of the host controller, too. A port is published on the same port of the Docker host if no
+// other container publishes it. Otherwise Docker chooses a random port.
+func publish(project *model.Project, host model.Host, plan []ports.Port) (map[string]struct{}, map[string][]PortBinding) {
+	published := publishedPorts(project, plan)
+	usage := make(map[int]int)
+	for _, port := range published {
+		usage[port.Port]++
+	}
+
+	exposed := make(map[string]struct{})
+	bindings := make(map[string][]PortBinding)
+	for _, port := range published {
+		if port.Host != host.Name {
+			continue
+		}
+		key := strconv.Itoa(port.Port) + "/tcp"
+		if _, ok := exposed[key]; ok {
+			continue
+		}
+		exposed[key] = struct{}{}
+		var hostPort string
+		if usage[port.Port] == 1 {
+			hostPort = strconv.Itoa(port.Port)
+		}
+		bindings[key] = []PortBinding{{HostPort: hostPort}}
+	}
+	return exposed, bindings
+}
+
+func publishedPorts(project *model.Project, plan []ports.Port) []ports.Port {
+	var published []ports.Port
+	for _, port := range plan {
+		if !port.IsWellKnown() {
+			continue
+		}
+		if port.Server == "" && !isDomainController(project, port.Host) {
+			continue
+		}
+		published = append(published, port)
+	}
+	return published
+}
+
+func isDomainController(project *model.Project, name string) bool {
+	for _, host := range project.Hosts {
+		if host.Name == name {
+			return host.DC
+		}
+	}
+	return false
+}
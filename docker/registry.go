@@ -0,0 +1,192 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"github.com/mitchellh/go-homedir"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// How often a push is retried and how long to wait before the first retry. The delay is
+// doubled for each retry.
+var pushRetries = 3
+var retryDelay = 2 * time.Second
+
+// The credentials sent to the registry.
+type AuthConfig struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	ServerAddress string `json:"serveraddress"`
+}
+
+// Tags the image with the given repository and tag.
+func (client *Client) TagImage(image, repository, tag string) error {
+	query := url.Values{"repo": {repository}, "tag": {tag}}
+	return client.doJson("POST", "/images/"+image+"/tag", query, nil, nil)
+}
+
+// Pushes the image to its registry. The push output is written to progress.
+func (client *Client) PushImage(repository, tag string, auth *AuthConfig, progress io.Writer) error {
+	if auth == nil {
+		auth = &AuthConfig{}
+	}
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"X-Registry-Auth": {base64.URLEncoding.EncodeToString(data)}}
+	query := url.Values{"tag": {tag}}
+	response, err := client.do("POST", "/images/"+repository+"/push", query, header, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return readStream(response.Body, progress)
+}
+
+// Tags the images of all hosts for the configured registry and pushes them. Failed pushes are
+// retried.
+func Push(client *Client, project *model.Project, progress io.Writer) error {
+	registry := project.Config.Registry
+	server := registryHost(registry.URL)
+	if server == "" {
+		return fmt.Errorf(`No registry configured. Please use "set config.registry.url <host:port>".`)
+	}
+	auth, err := RegistryAuth(registry)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range project.Hosts {
+		image := ImageName(project, host)
+		repository, tag := RegistryImage(registry, project, host)
+		if err := client.TagImage(image, repository, tag); err != nil {
+			return fmt.Errorf("Unable to tag image %s: %s", image, err)
+		}
+		fmt.Fprintf(progress, "%s: Pushing %s:%s\n", host.Name, repository, tag)
+
+		delay := retryDelay
+		for attempt := 1; ; attempt++ {
+			err = client.PushImage(repository, tag, auth, progress)
+			if err == nil {
+				break
+			}
+			if attempt > pushRetries {
+				return fmt.Errorf("Unable to push %s:%s after %d attempts: %s", repository, tag, attempt, err)
+			}
+			fmt.Fprintf(progress, "%s: Push failed: %s. Retrying in %s (%d/%d)\n", host.Name, err, delay, attempt, pushRetries)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return nil
+}
+
+// Returns the repository and tag of the image in the registry:
+// "<registry>/<repository>/<project>/<host>" and "<version>". The repository prefix is optional.
+func RegistryImage(registry model.Registry, project *model.Project, host model.Host) (string, string) {
+	repository := registryHost(registry.URL)
+	if prefix := strings.Trim(registry.Repository, "/"); prefix != "" {
+		repository += "/" + prefix
+	}
+	repository += "/" + repositoryName(project.Name) + "/" + repositoryName(host.Name)
+	return repository, tagName(project.Version)
+}
+
+// Reads the credentials referenced by the registry. Returns nil if no credentials are found.
+func RegistryAuth(registry model.Registry) (*AuthConfig, error) {
+	server := registryHost(registry.URL)
+	reference := registry.Credentials
+	if reference == "" {
+		return nil, nil
+	}
+
+	var credentials string
+	if strings.HasPrefix(reference, "env:") {
+		name := strings.TrimPrefix(reference, "env:")
+		credentials = os.Getenv(name)
+		if credentials == "" {
+			return nil, fmt.Errorf(`Unable to read registry credentials: Environment variable "%s" is not set.`, name)
+		}
+	} else {
+		var err error
+		credentials, err = dockerConfigAuth(reference, server)
+		if err != nil || credentials == "" {
+			return nil, err
+		}
+	}
+
+	parts := strings.SplitN(credentials, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, fmt.Errorf(`Unable to read registry credentials from "%s": Expected "username:password".`, reference)
+	}
+	return &AuthConfig{Username: parts[0], Password: parts[1], ServerAddress: server}, nil
+}
+
+// Returns the decoded "auth" entry for the given server of a Docker config file. A missing
+// file or a missing entry is not an error. Credential helpers are not supported: If the
+// credentials of the server are kept in a helper ("credsStore" or "credHelpers") and there's no
+// inline "auth" entry, an error is returned.
+func dockerConfigAuth(filename, server string) (string, error) {
+	filename, err := homedir.Expand(filename)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+		CredsStore  string            `json:"credsStore"`
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf(`Unable to read registry credentials from "%s": %s`, filename, err)
+	}
+	for key, entry := range config.Auths {
+		if registryHost(key) == server && entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return "", fmt.Errorf(`Unable to read registry credentials from "%s": %s`, filename, err)
+			}
+			return string(decoded), nil
+		}
+	}
+
+	helper := config.CredsStore
+	for key, name := range config.CredHelpers {
+		if registryHost(key) == server {
+			helper = name
+		}
+	}
+	if helper != "" {
+		return "", fmt.Errorf(`Unable to read registry credentials from "%s": The credentials of "%s" are kept in the credential helper "%s", which is not supported. Please use "env:<variable>" instead.`,
+			filename, server, helper)
+	}
+	return "", nil
+}
+
+// Strips the scheme and path from URLs like "https://registry.example.com/v1/".
+func registryHost(registryUrl string) string {
+	host := registryUrl
+	if index := strings.Index(host, "://"); index != -1 {
+		host = host[index+3:]
+	}
+	if index := strings.Index(host, "/"); index != -1 {
+		host = host[:index]
+	}
+	return host
+}
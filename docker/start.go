@@ -0,0 +1,296 @@
+package docker
+
+import (
+	"fmt"
+	"github.com/hpehl/whatunga/generate"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/ports"
+	"github.com/hpehl/whatunga/template"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The native management port of the domain controller. The slaves use this port to register
+// with the domain controller.
+var managementPort = 9999
+
+// The HTTP management port of the domain controller.
+var managementHttpPort = 9990
+
+// How long to wait for the management port of the domain controller and how often to check it.
+var startTimeout = 2 * time.Minute
+var pollInterval = 500 * time.Millisecond
+
+// Creates and starts one container per host. The containers are connected to a network named
+// after the project and are reachable by their host name, which is also the default of
+// "jboss.dc.address" in the slave configurations. The domain controller is started first. Once
+// its management port accepts connections, the slaves are started concurrently. The containers
+// are labeled with the project name, version and host name. Their ports are published as
+// calculated by the port planning. Images built with "--mount" get the deployments folder of the
+// project mounted. If something goes wrong, all containers created so far are removed again.
+func Start(client *Client, project *model.Project, progress io.Writer) error {
+	dc, slaves, err := domainController(project)
+	if err != nil {
+		return err
+	}
+	existing, err := Containers(client, project)
+	if err != nil {
+		return err
+	}
+	if len(existing) != 0 {
+		return fmt.Errorf(`There are already %d containers for this project. Use "docker rm" to remove them first.`, len(existing))
+	}
+	domain, err := template.ReadDomain(project.Config.Templates.Domain)
+	if err != nil {
+		return err
+	}
+	plan, err := ports.Plan(project, domain)
+	if err != nil {
+		return err
+	}
+	startup := &startup{client: client, project: project, progress: progress, network: NetworkName(project), plan: plan}
+	if err := startup.createNetwork(); err != nil {
+		return fmt.Errorf("Unable to create network %s: %s", startup.network, err)
+	}
+
+	container, err := startup.run(dc)
+	if err == nil {
+		err = startup.waitFor(dc, container)
+	}
+	if err != nil {
+		startup.rollback()
+		return fmt.Errorf("Unable to start domain controller %s: %s", dc.Name, err)
+	}
+
+	failures := make([]string, len(slaves))
+	var wg sync.WaitGroup
+	for i, slave := range slaves {
+		wg.Add(1)
+		go func(i int, slave model.Host) {
+			defer wg.Done()
+			if _, err := startup.run(slave); err != nil {
+				failures[i] = fmt.Sprintf("%s: %s", slave.Name, err)
+			}
+		}(i, slave)
+	}
+	wg.Wait()
+
+	var errors []string
+	for _, failure := range failures {
+		if failure != "" {
+			errors = append(errors, failure)
+		}
+	}
+	if len(errors) != 0 {
+		startup.rollback()
+		return fmt.Errorf("Unable to start all hosts. All containers have been removed again:\n\n    %s",
+			strings.Join(errors, "\n    "))
+	}
+	return nil
+}
+
+// Returns the domain controller and the remaining hosts.
+func domainController(project *model.Project) (model.Host, []model.Host, error) {
+	var dcs, slaves []model.Host
+	for _, host := range project.Hosts {
+		if host.DC {
+			dcs = append(dcs, host)
+		} else {
+			slaves = append(slaves, host)
+		}
+	}
+	if len(dcs) != 1 {
+		return model.Host{}, nil, fmt.Errorf("Exactly one host must be the domain controller, but found %d.", len(dcs))
+	}
+	return dcs[0], slaves, nil
+}
+
+// Keeps track of the created containers and network and serializes the progress output.
+type startup struct {
+	client         *Client
+	project        *model.Project
+	progress       io.Writer
+	network        string
+	createdNetwork string
+	plan           []ports.Port
+	mutex          sync.Mutex
+	created        []string
+}
+
+// Creates the project network unless it already exists.
+func (startup *startup) createNetwork() error {
+	network, err := startup.client.FindNetwork(startup.network)
+	if err != nil || network != nil {
+		return err
+	}
+	labels := map[string]string{ProjectLabel: startup.project.Name}
+	id, err := startup.client.CreateNetwork(startup.network, labels)
+	if err != nil {
+		return err
+	}
+	startup.createdNetwork = id
+	fmt.Fprintf(startup.progress, "Created network %s\n", startup.network)
+	return nil
+}
+
+// Creates and starts the container of the given host.
+func (startup *startup) run(host model.Host) (*Container, error) {
+	name := ContainerName(startup.project, host)
+	exposed, bindings := publish(startup.project, host, startup.plan)
+	config := ContainerConfig{
+		Image:        ImageName(startup.project, host),
+		Hostname:     host.Name,
+		Labels:       Labels(startup.project, host),
+		ExposedPorts: exposed,
+		HostConfig:   &HostConfig{NetworkMode: startup.network, PortBindings: bindings},
+		NetworkingConfig: &NetworkingConfig{EndpointsConfig: map[string]EndpointSettings{
+			startup.network: EndpointSettings{Aliases: []string{host.Name}},
+		}},
+	}
+	bind, err := startup.mount(config.Image)
+	if err != nil {
+		return nil, err
+	}
+	if bind != "" {
+		config.HostConfig.Binds = []string{bind}
+	}
+	id, err := startup.client.CreateContainer(name, config)
+	if err != nil {
+		return nil, err
+	}
+	startup.mutex.Lock()
+	startup.created = append(startup.created, id)
+	startup.mutex.Unlock()
+	startup.report(host, "Created container %s (%s) from %s", name, shortId(id), config.Image)
+
+	if err := startup.client.StartContainer(id); err != nil {
+		return nil, err
+	}
+	container, err := startup.client.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	startup.report(host, "Started container %s", shortId(id))
+	return container, nil
+}
+
+// Returns the bind of the deployments folder if the image was built with "--mount" or "" otherwise.
+func (startup *startup) mount(image string) (string, error) {
+	inspected, err := startup.client.InspectImage(image)
+	if err != nil {
+		return "", err
+	}
+	mountPoint := inspected.Config.Labels[generate.MountLabel]
+	if mountPoint == "" {
+		return "", nil
+	}
+	folder, err := filepath.Abs(generate.DeploymentsFolder)
+	if err != nil {
+		return "", err
+	}
+	return folder + ":" + mountPoint + ":ro", nil
+}
+
+// Waits until the management interface of the domain controller is up. If the HTTP management
+// port is published, it's checked on the Docker host, which works for remote daemons and Docker
+// Desktop, too. As the userland proxy of Docker accepts connections to published ports before
+// the domain controller listens, an HTTP response is required. The native management port is
+// checked at the address of the container in the project network, too, since it's reachable if
+// whatunga runs on the Docker host.
+func (startup *startup) waitFor(dc model.Host, container *Container) error {
+	var addresses []string
+	var probes []func() bool
+	if port := container.NetworkSettings.PublishedPort(managementHttpPort); port != "" {
+		url := "http://" + net.JoinHostPort(startup.client.Host(), port) + "/management"
+		addresses = append(addresses, url)
+		probes = append(probes, func() bool { return respondsHttp(url) })
+	}
+	if ip := container.NetworkSettings.Address(startup.network); ip != "" {
+		address := net.JoinHostPort(ip, strconv.Itoa(managementPort))
+		addresses = append(addresses, address)
+		probes = append(probes, func() bool { return acceptsTcp(address) })
+	}
+	if len(addresses) == 0 {
+		return fmt.Errorf("Container %s neither publishes the management port nor has an IP address.", shortId(container.ID))
+	}
+	address := strings.Join(addresses, " or ")
+	startup.report(dc, "Waiting for management port %s", address)
+
+	deadline := time.Now().Add(startTimeout)
+	for {
+		for _, probe := range probes {
+			if probe() {
+				startup.report(dc, "Domain controller is up")
+				return nil
+			}
+		}
+		current, err := startup.client.InspectContainer(container.ID)
+		if err != nil {
+			return err
+		}
+		if !current.State.Running {
+			return fmt.Errorf("Container %s stopped with exit code %d.", shortId(container.ID), current.State.ExitCode)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Management port %s was not opened within %s.", address, startTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Returns true if the URL answers with any HTTP response. Authentication is not needed, since
+// an unauthorized response proves that the management interface is up.
+func respondsHttp(url string) bool {
+	client := &http.Client{Timeout: pollInterval}
+	response, err := client.Get(url)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return true
+}
+
+func acceptsTcp(address string) bool {
+	conn, err := net.DialTimeout("tcp", address, pollInterval)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Removes all containers and the network created so far.
+func (startup *startup) rollback() {
+	for _, id := range startup.created {
+		if err := startup.client.RemoveContainer(id); err != nil {
+			fmt.Fprintf(startup.progress, "Unable to remove container %s: %s\n", shortId(id), err)
+		}
+	}
+	startup.created = nil
+	if startup.createdNetwork != "" {
+		if err := startup.client.RemoveNetwork(startup.createdNetwork); err != nil {
+			fmt.Fprintf(startup.progress, "Unable to remove network %s: %s\n", startup.network, err)
+		}
+		startup.createdNetwork = ""
+	}
+}
+
+func (startup *startup) report(host model.Host, format string, args ...interface{}) {
+	startup.mutex.Lock()
+	defer startup.mutex.Unlock()
+	fmt.Fprintf(startup.progress, "%s: %s\n", host.Name, fmt.Sprintf(format, args...))
+}
+
+// Returns the abbreviated container ID as shown by the docker CLI.
+func shortId(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
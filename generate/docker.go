@@ -0,0 +1,229 @@
+package generate
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The home directory of WildFly inside the base image.
+const JBossHome = "/opt/jboss/wildfly"
+
+// The file name of the Dockerfile inside the build context.
+const Dockerfile = "Dockerfile"
+
+// The folder with the WildFly / EAP distributions relative to the project directory.
+var DownloadsFolder = "downloads"
+
+// The public image with the JDK used as base image for the distributions in DownloadsFolder.
+const baseJdk = "jboss/base-jdk:7"
+
+// The directory the deployments folder of the project is mounted to.
+const MountPoint = "/opt/jboss/deployments"
+
+// Images which expect the deployments folder to be mounted carry this label. Its value is the
+// mount point.
+const MountLabel = "org.whatunga.mount"
+
+// Returns the target of the project based on the namespace of the domain template.
+func Target(project *model.Project) (model.Target, error) {
+	data, err := ioutil.ReadFile(project.Config.Templates.Domain)
+	if err != nil {
+		return model.Target{}, err
+	}
+	var root struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return model.Target{}, fmt.Errorf(`Unable to read domain template "%s": %s`, project.Config.Templates.Domain, err)
+	}
+	version := strings.TrimPrefix(root.XMLName.Space, "urn:jboss:domain:")
+	target, ok := model.ModelVersions[version]
+	if !ok {
+		return model.Target{}, fmt.Errorf(`Unsupported namespace "%s" in domain template "%s".`,
+			root.XMLName.Space, project.Config.Templates.Domain)
+	}
+	return target, nil
+}
+
+// Returns the files of the build context for the image of the given host. The domain
+// controller contains the domain configuration and the user property files in addition to the
+// host configuration. Unless mount is true, the deployment artifacts are copied to the content
+// repository of the domain controller. Otherwise the images are labeled with MountLabel and the
+// deployments reference the mounted deployments folder.
+func BuildContext(project *model.Project, host model.Host, mount bool) (map[string][]byte, error) {
+	target, err := Target(project)
+	if err != nil {
+		return nil, err
+	}
+	if target.Name != model.WildFly {
+		return nil, fmt.Errorf("There's no public base image for %s. Only %s is supported right now.", target, model.WildFly)
+	}
+	return buildContext(project, host, mount, fmt.Sprintf("FROM jboss/wildfly:%s.0.Final\n", target.Version))
+}
+
+// Returns the files of the build context like BuildContext, but the image is based on the
+// distribution in the downloads folder instead of a public base image. The distribution is part
+// of the build context and is installed to JBossHome. This works for all targets.
+func DistributionContext(project *model.Project, host model.Host, mount bool) (map[string][]byte, error) {
+	target, err := Target(project)
+	if err != nil {
+		return nil, err
+	}
+	filename, err := Distribution(target)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	root, err := zipRoot(data)
+	if err != nil {
+		return nil, fmt.Errorf(`Unable to read distribution "%s": %s`, filename, err)
+	}
+
+	name := filepath.Base(filename)
+	var install bytes.Buffer
+	fmt.Fprintf(&install, "FROM %s\n\n", baseJdk)
+	fmt.Fprintf(&install, "USER root\nADD %s /tmp/%s\n", name, name)
+	fmt.Fprintf(&install, "RUN unzip -q /tmp/%s -d /tmp && mv /tmp/%s %s && rm /tmp/%s && chown -R jboss:jboss %s\n",
+		name, root, JBossHome, name, JBossHome)
+	install.WriteString("USER jboss\n")
+	files, err := buildContext(project, host, mount, install.String())
+	if err != nil {
+		return nil, err
+	}
+	files[name] = data
+	return files, nil
+}
+
+// Returns the zip file of the distribution matching the target in the downloads folder, e.g.
+// "wildfly-8.1.0.Final.zip" or "jboss-eap-6.3.0.zip".
+func Distribution(target model.Target) (string, error) {
+	prefix := "wildfly-" + target.Version + "."
+	if target.Name == model.EAP {
+		prefix = "jboss-eap-" + target.Version + "."
+	}
+	infos, err := ioutil.ReadDir(DownloadsFolder)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(info.Name(), prefix) && strings.HasSuffix(info.Name(), ".zip") {
+			return filepath.Join(DownloadsFolder, info.Name()), nil
+		}
+	}
+	return "", fmt.Errorf(`Unable to find the distribution of %s. Please copy "%s*.zip" to the folder "%s".`,
+		target, prefix, DownloadsFolder)
+}
+
+// Returns the top level directory of the distribution.
+func zipRoot(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range reader.File {
+		if index := strings.Index(file.Name, "/"); index > 0 {
+			return file.Name[:index], nil
+		}
+	}
+	return "", fmt.Errorf("The distribution does not contain a top level directory.")
+}
+
+func buildContext(project *model.Project, host model.Host, mount bool, from string) (map[string][]byte, error) {
+	var err error
+	files := make(map[string][]byte)
+	files["host.xml"], err = Host(project, host)
+	if err != nil {
+		return nil, err
+	}
+	var contents []string
+	if host.DC {
+		if mount {
+			files["domain.xml"], err = MountedDomain(project)
+		} else {
+			files["domain.xml"], err = Domain(project)
+		}
+		if err != nil {
+			return nil, err
+		}
+		users, err := Users(project)
+		if err != nil {
+			return nil, err
+		}
+		for name, data := range users {
+			files[name] = data
+		}
+		if !mount {
+			if contents, err = addContents(project, files); err != nil {
+				return nil, err
+			}
+		}
+	}
+	files[Dockerfile] = dockerfile(from, host, contents, mount)
+	return files, nil
+}
+
+// Adds the deployment artifacts as "content/<sha1>" and returns their hashes.
+func addContents(project *model.Project, files map[string][]byte) ([]string, error) {
+	deployments, err := Deployments(project)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, deployment := range deployments {
+		data, err := readArtifact(deployment)
+		if err != nil {
+			return nil, err
+		}
+		hash := fmt.Sprintf("%x", sha1.Sum(data))
+		if _, ok := files["content/"+hash]; !ok {
+			files["content/"+hash] = data
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+// Returns the location of managed content in the content repository of the domain controller.
+func contentPath(hash string) string {
+	return JBossHome + "/domain/data/content/" + hash[:2] + "/" + hash[2:] + "/content"
+}
+
+// Returns the Dockerfile. The given instructions create the base image.
+func dockerfile(from string, host model.Host, contents []string, mount bool) []byte {
+	configuration := JBossHome + "/domain/configuration/"
+	var buffer bytes.Buffer
+	buffer.WriteString(from + "\n")
+	if mount {
+		fmt.Fprintf(&buffer, "LABEL %s=%s\n\n", MountLabel, MountPoint)
+	}
+	fmt.Fprintf(&buffer, "ADD host.xml %shost.xml\n", configuration)
+	if host.DC {
+		fmt.Fprintf(&buffer, "ADD domain.xml %sdomain.xml\n", configuration)
+		for _, name := range UserFiles {
+			fmt.Fprintf(&buffer, "ADD %s %s%s\n", name, configuration, name)
+		}
+	}
+	for _, hash := range contents {
+		fmt.Fprintf(&buffer, "ADD content/%s %s\n", hash, contentPath(hash))
+	}
+	owned := configuration
+	if len(contents) != 0 {
+		owned += " " + JBossHome + "/domain/data/"
+	}
+	fmt.Fprintf(&buffer, "\nUSER root\nRUN chown -R jboss:jboss %s\nUSER jboss\n\n", owned)
+	if host.DC {
+		buffer.WriteString("EXPOSE 9990 9999\n")
+	}
+	// additional arguments like the address of the domain controller are given as command
+	fmt.Fprintf(&buffer, `ENTRYPOINT ["%s/bin/domain.sh", "-b", "0.0.0.0", "-bmanagement", "0.0.0.0"]`+"\n", JBossHome)
+	return buffer.Bytes()
+}
@@ -0,0 +1,190 @@
+package generate
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the <server-groups> section and the top level <deployments> section of the domain template
+var serverGroupsSection = section("server-groups")
+var deploymentsSection = section("deployments")
+
+// The deployments folder of the project relative to the project directory.
+var DeploymentsFolder = "deployments"
+
+// Generates the domain configuration. The <server-groups> section of the domain template is
+// replaced with the server groups of the project and the deployments of all server groups are
+// added to the top level <deployments> section. All other parts of the template are kept as is.
+// The deployments reference the artifacts as managed content using their SHA-1 hash.
+func Domain(project *model.Project) ([]byte, error) {
+	return domain(project, false)
+}
+
+// Generates the domain configuration like Domain, but the deployments reference the artifacts
+// below MountPoint as unmanaged content. Use this configuration if the deployments folder of
+// the project is mounted into the containers.
+func MountedDomain(project *model.Project) ([]byte, error) {
+	return domain(project, true)
+}
+
+func domain(project *model.Project, mount bool) ([]byte, error) {
+	template := project.Config.Templates.Domain
+	data, err := ioutil.ReadFile(template)
+	if err != nil {
+		return nil, fmt.Errorf(`Unable to read domain template "%s": %s`, template, err)
+	}
+	location := serverGroupsSection.FindSubmatchIndex(data)
+	if location == nil {
+		return nil, fmt.Errorf(`Unable to generate domain configuration: Missing <server-groups> in domain template "%s"`, template)
+	}
+	indent := string(data[location[2]:location[3]])
+
+	deployments, err := domainDeployments(project, indent, mount)
+	if err != nil {
+		return nil, err
+	}
+	groups := serverGroups(project, indent)
+
+	// the top level deployments precede the server groups
+	var result []byte
+	before := data[:location[0]]
+	if existing := deploymentsSection.FindIndex(before); existing != nil {
+		result = append(result, before[:existing[0]]...)
+		result = append(result, deployments...)
+		result = append(result, skipLine(before[existing[1]:])...)
+	} else {
+		result = append(result, before...)
+		result = append(result, deployments...)
+	}
+	result = append(result, groups...)
+	result = append(result, data[location[1]:]...)
+	return result, nil
+}
+
+// Returns the deployments of all server groups. Deployments which are part of several server
+// groups are returned once.
+func Deployments(project *model.Project) ([]model.Deployment, error) {
+	var deployments []model.Deployment
+	var names = make(map[string]model.Deployment)
+	for _, serverGroup := range project.ServerGroups {
+		for _, deployment := range serverGroup.Deployments {
+			if existing, ok := names[deployment.Name]; ok {
+				if existing != deployment {
+					return nil, fmt.Errorf(`Unable to generate domain configuration: Deployment "%s" is defined differently in several server groups`, deployment.Name)
+				}
+				continue
+			}
+			names[deployment.Name] = deployment
+			deployments = append(deployments, deployment)
+		}
+	}
+	return deployments, nil
+}
+
+// Returns the top level deployments including the trailing new line or nil if the project
+// contains no deployments.
+func domainDeployments(project *model.Project, indent string, mount bool) ([]byte, error) {
+	deployments, err := Deployments(project)
+	if err != nil || len(deployments) == 0 {
+		return nil, err
+	}
+
+	writer := newXmlWriter(indent)
+	writer.open("deployments")
+	for _, deployment := range deployments {
+		writer.open("deployment", "name", deployment.Name, "runtime-name", deployment.RuntimeName)
+		if mount {
+			path, err := MountPath(deployment)
+			if err != nil {
+				return nil, err
+			}
+			info, err := os.Stat(deployment.Path)
+			if err != nil {
+				return nil, fmt.Errorf(`Unable to read artifact "%s" of deployment "%s": %s`, deployment.Path, deployment.Name, err)
+			}
+			// exploded deployments are directories
+			if info.IsDir() {
+				writer.empty("fs-exploded", "path", path)
+			} else {
+				writer.empty("fs-archive", "path", path)
+			}
+		} else {
+			hash, err := contentHash(deployment)
+			if err != nil {
+				return nil, err
+			}
+			writer.empty("content", "sha1", hash)
+		}
+		writer.close("deployment")
+	}
+	writer.close("deployments")
+	return append([]byte(indent), append(writer.Bytes(), '\n')...), nil
+}
+
+// Returns the server groups section without the leading indentation of the first line.
+func serverGroups(project *model.Project, indent string) []byte {
+	writer := newXmlWriter(indent)
+	if len(project.ServerGroups) == 0 {
+		writer.empty("server-groups")
+		return append([]byte(indent), writer.Bytes()...)
+	}
+
+	writer.open("server-groups")
+	for _, serverGroup := range project.ServerGroups {
+		writer.open("server-group", "name", serverGroup.Name, "profile", serverGroup.Profile)
+		writeJvm(writer, serverGroup.Jvm)
+		writer.empty("socket-binding-group", "ref", serverGroup.SocketBinding)
+		if len(serverGroup.Deployments) != 0 {
+			writer.open("deployments")
+			for _, deployment := range serverGroup.Deployments {
+				writer.empty("deployment", "name", deployment.Name, "runtime-name", deployment.RuntimeName)
+			}
+			writer.close("deployments")
+		}
+		writer.close("server-group")
+	}
+	writer.close("server-groups")
+	return append([]byte(indent), writer.Bytes()...)
+}
+
+// Returns the hex encoded SHA-1 hash of the deployment artifact which is used by WildFly / EAP
+// to locate managed content.
+func contentHash(deployment model.Deployment) (string, error) {
+	data, err := readArtifact(deployment)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha1.Sum(data)), nil
+}
+
+func readArtifact(deployment model.Deployment) ([]byte, error) {
+	data, err := ioutil.ReadFile(deployment.Path)
+	if err != nil {
+		return nil, fmt.Errorf(`Unable to read artifact "%s" of deployment "%s": %s`, deployment.Path, deployment.Name, err)
+	}
+	return data, nil
+}
+
+// Returns the path of the deployment artifact inside the containers if the deployments folder
+// is mounted to MountPoint. Artifacts outside the deployments folder cannot be mounted.
+func MountPath(deployment model.Deployment) (string, error) {
+	folder, err := filepath.Abs(DeploymentsFolder)
+	if err != nil {
+		return "", err
+	}
+	artifact, err := filepath.Abs(deployment.Path)
+	if err != nil {
+		return "", err
+	}
+	relative, err := filepath.Rel(folder, artifact)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf(`Unable to mount deployment "%s": The artifact "%s" is not part of the deployments folder "%s".`,
+			deployment.Name, deployment.Path, DeploymentsFolder)
+	}
+	return MountPoint + "/" + filepath.ToSlash(relative), nil
+}
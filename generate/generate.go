@@ -0,0 +1,151 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The default directory for generated files relative to the project directory.
+const OutputDir = "build"
+
+// Writes the data to the named file in the given directory. The name may contain
+// subdirectories. Missing directories are created.
+func WriteFile(dir string, name string, data []byte) error {
+	filename := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(filename), model.DirectoryPerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, model.FilePerm)
+}
+
+// ------------------------------------------------------ template sections
+
+// Returns a regular expression which matches the first element with the given name including
+// its content. The first group contains the indentation of the element.
+func section(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?s)([ \t]*)<` + name + `(?:\s[^>]*?)?(?:/>|>.*?</` + name + `>)`)
+}
+
+// Removes the remaining whitespace of the current line including the new line.
+func skipLine(data []byte) []byte {
+	trimmed := bytes.TrimLeft(data, " \t")
+	if len(trimmed) != 0 && trimmed[0] == '\n' {
+		return trimmed[1:]
+	}
+	return data
+}
+
+// ------------------------------------------------------ xml writer
+
+// Writes indented XML elements. Attributes are given as name / value pairs. Attributes with
+// an empty value are omitted.
+type xmlWriter struct {
+	buffer bytes.Buffer
+	indent string
+	depth  int
+}
+
+func newXmlWriter(indent string) *xmlWriter {
+	return &xmlWriter{indent: indent}
+}
+
+func (writer *xmlWriter) open(name string, attributes ...string) {
+	writer.element(name, attributes, ">")
+	writer.depth++
+}
+
+func (writer *xmlWriter) empty(name string, attributes ...string) {
+	writer.element(name, attributes, "/>")
+}
+
+func (writer *xmlWriter) close(name string) {
+	writer.depth--
+	writer.line()
+	writer.buffer.WriteString("</" + name + ">")
+}
+
+func (writer *xmlWriter) element(name string, attributes []string, end string) {
+	writer.line()
+	writer.buffer.WriteString("<" + name)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if attributes[i+1] != "" {
+			writer.buffer.WriteString(fmt.Sprintf(` %s="`, attributes[i]))
+			xml.EscapeText(&writer.buffer, []byte(attributes[i+1]))
+			writer.buffer.WriteString(`"`)
+		}
+	}
+	writer.buffer.WriteString(end)
+}
+
+// Starts a new line unless this is the first element.
+func (writer *xmlWriter) line() {
+	if writer.buffer.Len() != 0 {
+		writer.buffer.WriteString("\n" + writer.indent)
+	}
+	writer.buffer.WriteString(strings.Repeat("    ", writer.depth))
+}
+
+func (writer *xmlWriter) Bytes() []byte {
+	return writer.buffer.Bytes()
+}
+
+// ------------------------------------------------------ jvm
+
+func writeJvm(writer *xmlWriter, jvm *model.Jvm) {
+	if jvm == nil {
+		return
+	}
+	writer.open("jvm", "name", jvm.Name)
+	if jvm.Heap.Initial != "" || jvm.Heap.Max != "" {
+		writer.empty("heap", "size", jvmSize(jvm.Heap.Initial), "max-size", jvmSize(jvm.Heap.Max))
+	}
+	if jvm.PermGem != "" {
+		writer.empty("permgen", "size", jvmSize(jvm.PermGem), "max-size", jvmSize(jvm.PermGem))
+	}
+	if jvm.Stack != "" {
+		writer.empty("stack", "size", jvmSize(jvm.Stack))
+	}
+	if len(jvm.Options) != 0 {
+		writer.open("jvm-options")
+		for _, option := range jvm.Options {
+			writer.empty("option", "value", option)
+		}
+		writer.close("jvm-options")
+	}
+	writer.close("jvm")
+}
+
+// Turns sizes like "1GB" or "256MB" into the format used by the JVM ("1g", "256m").
+func jvmSize(size string) string {
+	size = strings.TrimSpace(size)
+	if len(size) > 2 && strings.EqualFold(size[len(size)-1:], "b") {
+		unit := size[len(size)-2]
+		if unit < '0' || unit > '9' {
+			size = size[:len(size)-1]
+		}
+	}
+	return strings.ToLower(size)
+}
+
+// ------------------------------------------------------ helper functions
+
+func escape(value string) string {
+	var buffer bytes.Buffer
+	xml.EscapeText(&buffer, []byte(value))
+	return buffer.String()
+}
+
+func concat(parts ...[]byte) []byte {
+	var result []byte
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result
+}
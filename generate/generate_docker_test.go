@@ -0,0 +1,159 @@
+package generate
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ------------------------------------------------------ docker tests
+
+// uses the project of GenerateDomainSuite
+func (s *GenerateDomainSuite) TestTarget(c *C) {
+	target, err := Target(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, model.Target{Name: model.WildFly, Version: "8.1"})
+}
+
+func (s *GenerateDomainSuite) TestBuildContextMaster(c *C) {
+	files, err := BuildContext(s.project, s.project.Hosts[0], false)
+	c.Assert(err, IsNil)
+	c.Assert(fileNames(files), DeepEquals, []string{
+		"Dockerfile", "application-roles.properties", "application-users.properties",
+		"content/0e823516992f85b1f9723e544697d43e5f5da3f3", "domain.xml", "host.xml",
+		"mgmt-groups.properties", "mgmt-users.properties",
+	})
+	c.Assert(string(files["content/0e823516992f85b1f9723e544697d43e5f5da3f3"]), Equals, "ticketmonster")
+	c.Assert(string(files[Dockerfile]), Equals, `FROM jboss/wildfly:8.1.0.Final
+
+ADD host.xml /opt/jboss/wildfly/domain/configuration/host.xml
+ADD domain.xml /opt/jboss/wildfly/domain/configuration/domain.xml
+ADD mgmt-users.properties /opt/jboss/wildfly/domain/configuration/mgmt-users.properties
+ADD mgmt-groups.properties /opt/jboss/wildfly/domain/configuration/mgmt-groups.properties
+ADD application-users.properties /opt/jboss/wildfly/domain/configuration/application-users.properties
+ADD application-roles.properties /opt/jboss/wildfly/domain/configuration/application-roles.properties
+ADD content/0e823516992f85b1f9723e544697d43e5f5da3f3 /opt/jboss/wildfly/domain/data/content/0e/823516992f85b1f9723e544697d43e5f5da3f3/content
+
+USER root
+RUN chown -R jboss:jboss /opt/jboss/wildfly/domain/configuration/ /opt/jboss/wildfly/domain/data/
+USER jboss
+
+EXPOSE 9990 9999
+ENTRYPOINT ["/opt/jboss/wildfly/bin/domain.sh", "-b", "0.0.0.0", "-bmanagement", "0.0.0.0"]
+`)
+}
+
+func (s *GenerateDomainSuite) TestBuildContextSlave(c *C) {
+	files, err := BuildContext(s.project, s.project.Hosts[1], false)
+	c.Assert(err, IsNil)
+	c.Assert(fileNames(files), DeepEquals, []string{"Dockerfile", "host.xml"})
+	c.Assert(strings.Contains(string(files["host.xml"]), `<host name="slave"`), Equals, true)
+	c.Assert(strings.Contains(string(files[Dockerfile]), "domain.xml"), Equals, false)
+}
+
+func (s *GenerateDomainSuite) TestBuildContextMounted(c *C) {
+	files, err := BuildContext(s.project, s.project.Hosts[0], true)
+	c.Assert(err, IsNil)
+	c.Assert(fileNames(files), DeepEquals, []string{
+		"Dockerfile", "application-roles.properties", "application-users.properties", "domain.xml",
+		"host.xml", "mgmt-groups.properties", "mgmt-users.properties",
+	})
+	c.Assert(strings.Contains(string(files["domain.xml"]), `<fs-archive path="/opt/jboss/deployments/ticketmonster.ear"/>`), Equals, true)
+	c.Assert(strings.Contains(string(files[Dockerfile]), "LABEL org.whatunga.mount=/opt/jboss/deployments\n"), Equals, true)
+	c.Assert(strings.Contains(string(files[Dockerfile]), "content"), Equals, false)
+
+	files, err = BuildContext(s.project, s.project.Hosts[1], true)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(files[Dockerfile]), "LABEL org.whatunga.mount=/opt/jboss/deployments\n"), Equals, true)
+}
+
+func (s *GenerateDomainSuite) TestDistributionContext(c *C) {
+	writeDistribution(c, "wildfly-8.1.0.Final.zip", "wildfly-8.1.0.Final")
+	files, err := DistributionContext(s.project, s.project.Hosts[1], false)
+	c.Assert(err, IsNil)
+	c.Assert(fileNames(files), DeepEquals, []string{"Dockerfile", "host.xml", "wildfly-8.1.0.Final.zip"})
+	c.Assert(string(files[Dockerfile]), Equals, `FROM jboss/base-jdk:7
+
+USER root
+ADD wildfly-8.1.0.Final.zip /tmp/wildfly-8.1.0.Final.zip
+RUN unzip -q /tmp/wildfly-8.1.0.Final.zip -d /tmp && mv /tmp/wildfly-8.1.0.Final /opt/jboss/wildfly && rm /tmp/wildfly-8.1.0.Final.zip && chown -R jboss:jboss /opt/jboss/wildfly
+USER jboss
+
+ADD host.xml /opt/jboss/wildfly/domain/configuration/host.xml
+
+USER root
+RUN chown -R jboss:jboss /opt/jboss/wildfly/domain/configuration/
+USER jboss
+
+ENTRYPOINT ["/opt/jboss/wildfly/bin/domain.sh", "-b", "0.0.0.0", "-bmanagement", "0.0.0.0"]
+`)
+}
+
+func (s *GenerateDomainSuite) TestDistributionContextEap(c *C) {
+	writeDomainNamespace(c, s.project, "urn:jboss:domain:1.6")
+	writeDistribution(c, "wildfly-8.1.0.Final.zip", "wildfly-8.1.0.Final")
+	writeDistribution(c, "jboss-eap-6.3.0.zip", "jboss-eap-6.3")
+	files, err := DistributionContext(s.project, s.project.Hosts[0], false)
+	c.Assert(err, IsNil)
+	c.Assert(files["jboss-eap-6.3.0.zip"], NotNil)
+	c.Assert(files["wildfly-8.1.0.Final.zip"], IsNil)
+	c.Assert(strings.Contains(string(files[Dockerfile]), "mv /tmp/jboss-eap-6.3 /opt/jboss/wildfly"), Equals, true)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *GenerateDomainSuite) TestDistributionMissing(c *C) {
+	DownloadsFolder = c.MkDir()
+	_, err := DistributionContext(s.project, s.project.Hosts[0], false)
+	c.Assert(err, ErrorMatches, `Unable to find the distribution of wildfly:8.1. Please copy "wildfly-8.1.\*.zip" to the folder ".*".`)
+}
+
+func (s *GenerateDomainSuite) TestBuildContextEap(c *C) {
+	writeDomainNamespace(c, s.project, "urn:jboss:domain:1.6")
+	_, err := BuildContext(s.project, s.project.Hosts[0], false)
+	c.Assert(err, ErrorMatches, "There's no public base image for eap:6.3. Only wildfly is supported right now.")
+}
+
+func (s *GenerateDomainSuite) TestTargetUnknownNamespace(c *C) {
+	writeDomainNamespace(c, s.project, "urn:jboss:domain:0.8")
+	_, err := Target(s.project)
+	c.Assert(err, ErrorMatches, `Unsupported namespace "urn:jboss:domain:0.8" in domain template ".*domain.xml".`)
+}
+
+// ------------------------------------------------------ helper functions
+
+func fileNames(files map[string][]byte) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeDomainNamespace(c *C, project *model.Project, namespace string) {
+	data, err := ioutil.ReadFile(project.Config.Templates.Domain)
+	c.Assert(err, IsNil)
+	data = []byte(strings.Replace(string(data), "urn:jboss:domain:2.1", namespace, 1))
+	c.Assert(ioutil.WriteFile(project.Config.Templates.Domain, data, model.FilePerm), IsNil)
+}
+
+// Writes a distribution with a single file below the given root directory to a new downloads
+// folder unless the downloads folder has already been created by the current test.
+func writeDistribution(c *C, name, root string) {
+	if DownloadsFolder == "downloads" {
+		DownloadsFolder = c.MkDir()
+	}
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	file, err := writer.Create(root + "/bin/domain.sh")
+	c.Assert(err, IsNil)
+	file.Write([]byte("#!/bin/sh"))
+	c.Assert(writer.Close(), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(DownloadsFolder, name), buffer.Bytes(), model.FilePerm), IsNil)
+}
@@ -0,0 +1,177 @@
+package generate
+
+import (
+	"encoding/xml"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/template"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ------------------------------------------------------ setup
+
+type GenerateDomainSuite struct {
+	project *model.Project
+}
+
+func (s *GenerateDomainSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	for _, name := range []string{"domain.xml", "host-master.xml", "host-slave.xml"} {
+		data, err := template.Asset("templates/wildfly/8.1/" + name)
+		c.Assert(err, IsNil)
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), data, model.FilePerm), IsNil)
+	}
+	// the artifacts are part of the deployments folder
+	DeploymentsFolder = dir
+	artifact := filepath.Join(dir, "ticketmonster.ear")
+	c.Assert(ioutil.WriteFile(artifact, []byte("ticketmonster"), model.FilePerm), IsNil)
+
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		Config: model.Config{
+			Templates: model.Templates{
+				Domain:     filepath.Join(dir, "domain.xml"),
+				HostMaster: filepath.Join(dir, "host-master.xml"),
+				HostSlave:  filepath.Join(dir, "host-slave.xml"),
+			},
+			ConsoleUser: model.User{Name: "admin", Password: "passw0rd_"},
+			DomainUser:  model.User{Name: "domain", Password: "s3cret"},
+		},
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{
+				Name:          "dev",
+				Profile:       "default",
+				SocketBinding: "standard-sockets",
+				Deployments: []model.Deployment{
+					model.Deployment{Name: "ticketmonster-ear", RuntimeName: "ticketmonster.ear", Path: artifact},
+				},
+			},
+			model.ServerGroup{
+				Name:          "prod",
+				Profile:       "full",
+				SocketBinding: "full-sockets",
+				Jvm: &model.Jvm{
+					Name:    "prod-jvm",
+					Heap:    model.BoundedMemory{Initial: "1GB", Max: "2GB"},
+					PermGem: "256MB",
+					Options: []string{"-server"},
+				},
+			},
+		},
+		Hosts: []model.Host{
+			model.Host{Name: "master", DC: true, Jvm: &model.Jvm{Name: "master-jvm", Heap: model.BoundedMemory{Initial: "64m", Max: "256m"}}},
+			model.Host{Name: "slave", Servers: []model.Server{
+				model.Server{Name: "server0", ServerGroup: "dev", AutoStart: true},
+				model.Server{Name: "server1", ServerGroup: "prod", PortOffset: 50, Jvm: &model.Jvm{Name: "server1-jvm"}},
+			}},
+		},
+	}
+}
+
+func (s *GenerateDomainSuite) TearDownTest(c *C) {
+	DeploymentsFolder = "deployments"
+	DownloadsFolder = "downloads"
+}
+
+var _ = Suite(&GenerateDomainSuite{})
+
+// ------------------------------------------------------ domain tests
+
+func (s *GenerateDomainSuite) TestDomainServerGroups(c *C) {
+	data, err := Domain(s.project)
+	c.Assert(err, IsNil)
+	domain := string(data)
+
+	c.Assert(strings.Contains(domain, `    <server-groups>
+        <server-group name="dev" profile="default">
+            <socket-binding-group ref="standard-sockets"/>
+            <deployments>
+                <deployment name="ticketmonster-ear" runtime-name="ticketmonster.ear"/>
+            </deployments>
+        </server-group>
+        <server-group name="prod" profile="full">
+            <jvm name="prod-jvm">
+                <heap size="1g" max-size="2g"/>
+                <permgen size="256m" max-size="256m"/>
+                <jvm-options>
+                    <option value="-server"/>
+                </jvm-options>
+            </jvm>
+            <socket-binding-group ref="full-sockets"/>
+        </server-group>
+    </server-groups>
+</domain>`), Equals, true)
+	c.Assert(strings.Contains(domain, "main-server-group"), Equals, false)
+}
+
+func (s *GenerateDomainSuite) TestDomainDeployments(c *C) {
+	data, err := Domain(s.project)
+	c.Assert(err, IsNil)
+
+	c.Assert(strings.Contains(string(data), `    </socket-binding-groups>
+    <deployments>
+        <deployment name="ticketmonster-ear" runtime-name="ticketmonster.ear">
+            <content sha1="0e823516992f85b1f9723e544697d43e5f5da3f3"/>
+        </deployment>
+    </deployments>
+    <server-groups>`), Equals, true)
+}
+
+func (s *GenerateDomainSuite) TestDomainKeepsTemplate(c *C) {
+	data, err := Domain(s.project)
+	c.Assert(err, IsNil)
+	domain := string(data)
+
+	c.Assert(strings.Contains(domain, `<extension module="org.wildfly.extension.undertow"/>`), Equals, true)
+	c.Assert(strings.Contains(domain, `<profile name="full-ha">`), Equals, true)
+	c.Assert(strings.Contains(domain, `<!-- Needed for server groups using the 'default' profile  -->`), Equals, true)
+	var root struct{ XMLName xml.Name }
+	c.Assert(xml.Unmarshal(data, &root), IsNil)
+	c.Assert(root.XMLName.Local, Equals, "domain")
+}
+
+func (s *GenerateDomainSuite) TestDomainIsStable(c *C) {
+	data, err := Domain(s.project)
+	c.Assert(err, IsNil)
+
+	// using the generated domain as template must not change anything
+	c.Assert(ioutil.WriteFile(s.project.Config.Templates.Domain, data, model.FilePerm), IsNil)
+	again, err := Domain(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(string(again), Equals, string(data))
+}
+
+func (s *GenerateDomainSuite) TestMountedDomain(c *C) {
+	dir := DeploymentsFolder
+	c.Assert(os.Mkdir(filepath.Join(dir, "exploded.war"), model.DirectoryPerm), IsNil)
+	s.project.ServerGroups[1].Deployments = []model.Deployment{
+		model.Deployment{Name: "exploded-war", RuntimeName: "exploded.war", Path: filepath.Join(dir, "exploded.war")},
+	}
+
+	data, err := MountedDomain(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), `    <deployments>
+        <deployment name="ticketmonster-ear" runtime-name="ticketmonster.ear">
+            <fs-archive path="/opt/jboss/deployments/ticketmonster.ear"/>
+        </deployment>
+        <deployment name="exploded-war" runtime-name="exploded.war">
+            <fs-exploded path="/opt/jboss/deployments/exploded.war"/>
+        </deployment>
+    </deployments>`), Equals, true)
+}
+
+func (s *GenerateDomainSuite) TestMountedDomainOutsideDeployments(c *C) {
+	s.project.ServerGroups[0].Deployments[0].Path = "/opt/other/ticketmonster.ear"
+	_, err := MountedDomain(s.project)
+	c.Assert(err, ErrorMatches, `Unable to mount deployment "ticketmonster-ear": The artifact "/opt/other/ticketmonster.ear" is not part of the deployments folder ".*".`)
+}
+
+func (s *GenerateDomainSuite) TestDomainMissingArtifact(c *C) {
+	s.project.ServerGroups[0].Deployments[0].Path = "/does/not/exist.ear"
+	_, err := Domain(s.project)
+	c.Assert(err, ErrorMatches, `Unable to read artifact "/does/not/exist.ear" of deployment "ticketmonster-ear": .*`)
+}
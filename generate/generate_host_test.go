@@ -0,0 +1,84 @@
+package generate
+
+import (
+	"encoding/xml"
+	. "gopkg.in/check.v1"
+	"strings"
+)
+
+// ------------------------------------------------------ host tests
+
+// uses the project of GenerateDomainSuite
+func (s *GenerateDomainSuite) TestHostMaster(c *C) {
+	data, err := Host(s.project, s.project.Hosts[0])
+	c.Assert(err, IsNil)
+	host := string(data)
+
+	c.Assert(strings.Contains(host, `<host name="master" xmlns="urn:jboss:domain:2.1">`), Equals, true)
+	c.Assert(strings.Contains(host, `<local/>`), Equals, true)
+	c.Assert(strings.Contains(host, `    <jvms>
+        <jvm name="master-jvm">
+            <heap size="64m" max-size="256m"/>
+        </jvm>
+    </jvms>`), Equals, true)
+	c.Assert(strings.Contains(host, "<servers"), Equals, false)
+	assertHostXml(c, data)
+}
+
+func (s *GenerateDomainSuite) TestHostMasterWithServers(c *C) {
+	s.project.Hosts[0].Servers = s.project.Hosts[1].Servers
+	data, err := Host(s.project, s.project.Hosts[0])
+	c.Assert(err, IsNil)
+
+	c.Assert(strings.Contains(string(data), `    <servers>
+        <server name="server0" group="dev" auto-start="true"/>
+        <server name="server1" group="prod" auto-start="false">
+            <jvm name="server1-jvm"/>
+            <socket-bindings port-offset="50"/>
+        </server>
+    </servers>
+</host>`), Equals, true)
+	assertHostXml(c, data)
+}
+
+func (s *GenerateDomainSuite) TestHostSlave(c *C) {
+	data, err := Host(s.project, s.project.Hosts[1])
+	c.Assert(err, IsNil)
+	host := string(data)
+
+	c.Assert(strings.Contains(host, `<host name="slave" xmlns="urn:jboss:domain:2.1">`), Equals, true)
+	c.Assert(strings.Contains(host, `<remote host="${jboss.dc.address:master}" port="${jboss.dc.port:9999}" username="domain" security-realm="SlaveRealm"/>`), Equals, true)
+	c.Assert(strings.Contains(host, `<secret value="czNjcmV0" />`), Equals, true)
+	c.Assert(strings.Contains(host, `    <jvms>
+        <jvm name="server1-jvm">
+        </jvm>
+    </jvms>`), Equals, true)
+	c.Assert(strings.Contains(host, `    <servers>
+        <server name="server0" group="dev" auto-start="true"/>
+        <server name="server1" group="prod" auto-start="false">
+            <jvm name="server1-jvm"/>
+            <socket-bindings port-offset="50"/>
+        </server>
+    </servers>`), Equals, true)
+	c.Assert(strings.Contains(host, "main-server-group"), Equals, false)
+	assertHostXml(c, data)
+}
+
+func (s *GenerateDomainSuite) TestHostSlaveWithoutServers(c *C) {
+	s.project.Hosts[1].Servers = nil
+	data, err := Host(s.project, s.project.Hosts[1])
+	c.Assert(err, IsNil)
+
+	// the JVMs of the template are kept, the sample servers are removed
+	c.Assert(strings.Contains(string(data), `<jvm name="default">`), Equals, true)
+	c.Assert(strings.Contains(string(data), "<servers"), Equals, false)
+	assertHostXml(c, data)
+}
+
+// ------------------------------------------------------ helper functions
+
+func assertHostXml(c *C, data []byte) {
+	var root struct{ XMLName xml.Name }
+	c.Assert(xml.Unmarshal(data, &root), IsNil)
+	c.Assert(root.XMLName.Local, Equals, "host")
+}
@@ -0,0 +1,9 @@
+package generate
+
+import (
+	. "gopkg.in/check.v1"
+	"testing"
+)
+
+// triggers all tests in this package
+func TestGenerate(t *testing.T) { TestingT(t) }
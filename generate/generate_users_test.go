@@ -0,0 +1,60 @@
+package generate
+
+import (
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+	"strings"
+)
+
+// ------------------------------------------------------ user tests
+
+// uses the project of GenerateDomainSuite
+func (s *GenerateDomainSuite) TestUsers(c *C) {
+	s.project.Users = []model.User{{Name: "alice", Password: "secret"}}
+	files, err := Users(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, len(UserFiles))
+
+	c.Assert(properties(files[MgmtUsers]), DeepEquals, []string{
+		"admin=c6ea5554c6cb5d7d1c3437bb9660cf28",
+		"domain=6a0009f44ed3cb84cea6a09a1569ba86",
+	})
+	c.Assert(strings.Contains(string(files[MgmtUsers]), "#$REALM_NAME=ManagementRealm$"), Equals, true)
+	c.Assert(properties(files[MgmtGroups]), DeepEquals, []string{"admin=", "domain="})
+	c.Assert(properties(files[ApplicationUsers]), DeepEquals, []string{"alice=e19ca7cb3c0a7f3c0bc4102137643489"})
+	c.Assert(strings.Contains(string(files[ApplicationUsers]), "#$REALM_NAME=ApplicationRealm$"), Equals, true)
+	c.Assert(properties(files[ApplicationRoles]), DeepEquals, []string{"alice="})
+}
+
+func (s *GenerateDomainSuite) TestUsersWithoutApplicationUsers(c *C) {
+	files, err := Users(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(properties(files[ApplicationUsers]), HasLen, 0)
+	c.Assert(properties(files[ApplicationRoles]), HasLen, 0)
+}
+
+func (s *GenerateDomainSuite) TestUsersEscaping(c *C) {
+	c.Assert(propertyKey("foo"), Equals, "foo")
+	c.Assert(propertyKey(`a=b:c d\e`), Equals, `a\=b\:c\ d\\e`)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *GenerateDomainSuite) TestUsersEmptyName(c *C) {
+	s.project.Users = []model.User{{Name: "", Password: "secret"}}
+	_, err := Users(s.project)
+	c.Assert(err, ErrorMatches, "Unable to generate users: Usernames must not be empty.")
+}
+
+// ------------------------------------------------------ helper functions
+
+// Returns the non-comment lines of a property file.
+func properties(data []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
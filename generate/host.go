@@ -0,0 +1,159 @@
+package generate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+)
+
+var hostElement = regexp.MustCompile(`<host\b[^>]*>`)
+var nameAttribute = regexp.MustCompile(`\sname="[^"]*"`)
+var remoteUsername = regexp.MustCompile(`(<remote\b[^>]*?\busername=")[^"]*(")`)
+var secretValue = regexp.MustCompile(`(<secret\b[^>]*?\bvalue=")[^"]*(")`)
+var jvmsSection = section("jvms")
+var serversSection = section("servers")
+var dcAddress = regexp.MustCompile(`\$\{jboss\.dc\.address\}`)
+var hostEnd = regexp.MustCompile(`\n[ \t]*</host>`)
+
+// Returns the name of the host configuration file for the given host.
+func HostFile(host model.Host) string {
+	return "host-" + host.Name + ".xml"
+}
+
+// Generates the host configuration for the given host. The host master template is used for
+// the domain controller, the host slave template for all other hosts. The <jvms> and <servers>
+// sections of the template are replaced with the JVMs and servers of the host. For slaves the
+// credentials of the domain user are used to connect to the domain controller. Its address
+// defaults to the host name of the domain controller.
+func Host(project *model.Project, host model.Host) ([]byte, error) {
+	template := project.Config.Templates.HostSlave
+	if host.DC {
+		template = project.Config.Templates.HostMaster
+	}
+	data, err := ioutil.ReadFile(template)
+	if err != nil {
+		return nil, fmt.Errorf(`Unable to read host template "%s": %s`, template, err)
+	}
+
+	// host name
+	location := hostElement.FindIndex(data)
+	if location == nil {
+		return nil, fmt.Errorf(`Unable to generate host configuration: Missing <host> in host template "%s"`, template)
+	}
+	hostTag := data[location[0]:location[1]]
+	name := []byte(` name="` + escape(host.Name) + `"`)
+	if nameAttribute.Match(hostTag) {
+		hostTag = nameAttribute.ReplaceAllLiteral(hostTag, name)
+	} else {
+		hostTag = append([]byte("<host"), append(name, hostTag[len("<host"):]...)...)
+	}
+	data = concat(data[:location[0]], hostTag, data[location[1]:])
+
+	// connection to the domain controller
+	if !host.DC {
+		data = replaceAttribute(data, remoteUsername, escape(project.Config.DomainUser.Name))
+		data = replaceAttribute(data, secretValue, base64.StdEncoding.EncodeToString([]byte(project.Config.DomainUser.Password)))
+		// the domain controller is reachable using its host name
+		for _, dc := range project.Hosts {
+			if dc.DC {
+				data = dcAddress.ReplaceAllLiteral(data, []byte("${jboss.dc.address:"+escape(dc.Name)+"}"))
+				break
+			}
+		}
+	}
+
+	// jvms
+	jvms := hostJvms(host)
+	if len(jvms) != 0 {
+		if location := jvmsSection.FindSubmatchIndex(data); location != nil {
+			indent := string(data[location[2]:location[3]])
+			data = concat(data[:location[0]], writeJvms(jvms, indent), data[location[1]:])
+		} else {
+			data = insertBeforeEnd(data, func(indent string) []byte { return writeJvms(jvms, indent) })
+		}
+	}
+
+	// servers
+	if location := serversSection.FindSubmatchIndex(data); location != nil {
+		if len(host.Servers) == 0 {
+			data = concat(data[:location[0]], skipLine(data[location[1]:]))
+		} else {
+			indent := string(data[location[2]:location[3]])
+			data = concat(data[:location[0]], writeServers(host.Servers, indent), data[location[1]:])
+		}
+	} else if len(host.Servers) != 0 {
+		data = insertBeforeEnd(data, func(indent string) []byte { return writeServers(host.Servers, indent) })
+	}
+	return data, nil
+}
+
+// Returns the JVM of the host followed by the JVMs of its servers. JVMs with the same name are
+// only included once.
+func hostJvms(host model.Host) []*model.Jvm {
+	var jvms []*model.Jvm
+	var names = make(map[string]bool)
+	candidates := []*model.Jvm{host.Jvm}
+	for _, server := range host.Servers {
+		candidates = append(candidates, server.Jvm)
+	}
+	for _, jvm := range candidates {
+		if jvm != nil && !names[jvm.Name] {
+			names[jvm.Name] = true
+			jvms = append(jvms, jvm)
+		}
+	}
+	return jvms
+}
+
+func writeJvms(jvms []*model.Jvm, indent string) []byte {
+	writer := newXmlWriter(indent)
+	writer.open("jvms")
+	for _, jvm := range jvms {
+		writeJvm(writer, jvm)
+	}
+	writer.close("jvms")
+	return append([]byte(indent), writer.Bytes()...)
+}
+
+func writeServers(servers []model.Server, indent string) []byte {
+	writer := newXmlWriter(indent)
+	writer.open("servers")
+	for _, server := range servers {
+		attributes := []string{"name", server.Name, "group", server.ServerGroup, "auto-start", strconv.FormatBool(server.AutoStart)}
+		if server.Jvm == nil && server.PortOffset == 0 {
+			writer.empty("server", attributes...)
+			continue
+		}
+		writer.open("server", attributes...)
+		if server.Jvm != nil {
+			writer.empty("jvm", "name", server.Jvm.Name)
+		}
+		if server.PortOffset != 0 {
+			writer.empty("socket-bindings", "port-offset", strconv.Itoa(server.PortOffset))
+		}
+		writer.close("server")
+	}
+	writer.close("servers")
+	return append([]byte(indent), writer.Bytes()...)
+}
+
+// Replaces the attribute value matched by the regular expression. The expression must contain
+// two groups: One for everything before and one for everything after the value.
+func replaceAttribute(data []byte, attribute *regexp.Regexp, value string) []byte {
+	return attribute.ReplaceAllFunc(data, func(match []byte) []byte {
+		groups := attribute.FindSubmatch(match)
+		return concat(groups[1], []byte(value), groups[2])
+	})
+}
+
+// Inserts the generated section before the closing </host> tag.
+func insertBeforeEnd(data []byte, generate func(indent string) []byte) []byte {
+	end := hostEnd.FindIndex(data)
+	if end == nil {
+		return data
+	}
+	return concat(data[:end[0]], []byte("\n"), generate("    "), data[end[0]:])
+}
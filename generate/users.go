@@ -0,0 +1,97 @@
+package generate
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+)
+
+// The security realms of the properties based user store.
+const (
+	ManagementRealm  = "ManagementRealm"
+	ApplicationRealm = "ApplicationRealm"
+)
+
+// The property files of the properties based user store.
+const (
+	MgmtUsers        = "mgmt-users.properties"
+	MgmtGroups       = "mgmt-groups.properties"
+	ApplicationUsers = "application-users.properties"
+	ApplicationRoles = "application-roles.properties"
+)
+
+// The names of all property files in the order they're generated.
+var UserFiles = []string{MgmtUsers, MgmtGroups, ApplicationUsers, ApplicationRoles}
+
+// Generates the property files of the properties based user store as written by the add-user
+// script. The console and the domain user are added to the management realm, the users of the
+// project to the application realm. The result maps the file names to their content.
+func Users(project *model.Project) (map[string][]byte, error) {
+	management := []model.User{project.Config.ConsoleUser, project.Config.DomainUser}
+	for _, user := range append(management, project.Users...) {
+		if user.Name == "" {
+			return nil, fmt.Errorf("Unable to generate users: Usernames must not be empty.")
+		}
+	}
+
+	return map[string][]byte{
+		MgmtUsers:        userProperties(ManagementRealm, management),
+		MgmtGroups:       groupProperties(ManagementRealm, "group", management),
+		ApplicationUsers: userProperties(ApplicationRealm, project.Users),
+		ApplicationRoles: groupProperties(ApplicationRealm, "role", project.Users),
+	}, nil
+}
+
+// Returns the hex encoded MD5 hash of "username:realm:password" as used by WildFly / EAP.
+func passwordHash(realm string, user model.User) string {
+	hash := md5.Sum([]byte(user.Name + ":" + realm + ":" + user.Password))
+	return hex.EncodeToString(hash[:])
+}
+
+func userProperties(realm string, users []model.User) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `#
+# Properties declaration of users for the realm '%s'.
+#
+# The format of this file is as follows: -
+# username=HEX( MD5( username ':' realm ':' password))
+#
+#$REALM_NAME=%s$ This line is used by the add-user utility to identify the realm name already used in this file.
+#
+`, realm, realm)
+	for _, user := range users {
+		fmt.Fprintf(&buffer, "%s=%s\n", propertyKey(user.Name), passwordHash(realm, user))
+	}
+	return buffer.Bytes()
+}
+
+// The users don't have any groups or roles yet, so only empty assignments are written.
+func groupProperties(realm string, kind string, users []model.User) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `#
+# Properties declaration of users %ss for the realm '%s'.
+#
+# The format of this file is as follows: -
+# username=%s1,%s2,...
+#
+`, kind, realm, kind, kind)
+	for _, user := range users {
+		fmt.Fprintf(&buffer, "%s=\n", propertyKey(user.Name))
+	}
+	return buffer.Bytes()
+}
+
+// Escapes the characters which have a special meaning in keys of Java property files.
+func propertyKey(key string) string {
+	var buffer bytes.Buffer
+	for _, r := range key {
+		switch r {
+		case '=', ':', ' ', '#', '!', '\\':
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(r)
+	}
+	return buffer.String()
+}
@@ -126,6 +126,9 @@ func NewProject(directory string, name string, version string, target Target) (*
 				Password: "passw0rd_",
 			},
 			DockerRemoteAPI: "unix:///var/run/docker.sock",
+			Registry: Registry{
+				Credentials: "~/.docker/config.json",
+			},
 		},
 		ServerGroups: []ServerGroup{},
 		Hosts:        []Host{},
@@ -224,18 +227,12 @@ func (project *Project) Load() error {
 	return nil
 }
 
-func (project *Project) Set(path string, value []string) {
-	if path == "" {
-		return
-	}
-	// TODO Use reflection to set values
-}
-
 type Config struct {
 	Templates       Templates `json:"templates"`
 	ConsoleUser     User      `json:"console-user"`
 	DomainUser      User      `json:"domain-user"`
 	DockerRemoteAPI string    `json:"docker-remote-api"`
+	Registry        Registry  `json:"registry"`
 }
 
 type Templates struct {
@@ -244,6 +241,15 @@ type Templates struct {
 	HostSlave  string `json:"host-slave"`
 }
 
+// The Docker registry used to push the images. The credentials are not stored in the project.
+// Instead they reference a Docker config file or an environment variable "env:NAME" which
+// contains "username:password".
+type Registry struct {
+	URL         string `json:"url"`
+	Repository  string `json:"repository"`
+	Credentials string `json:"credentials"`
+}
+
 type ServerGroup struct {
 	Name          string       `json:"name"`
 	Profile       string       `json:"profile"`
@@ -0,0 +1,327 @@
+package path
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A predicate used as index like "servers[?server-group==prod && auto-start==true]". Comparisons
+// name a JSON-tagged field of the element (nested fields are separated by dots), an operator and
+// a value. Supported operators are "==", "!=", "<", "<=", ">", ">=" and "=~" (regex match).
+// Comparisons can be combined using "&&", "||" and parentheses. Strings can be enclosed in double
+// quotes. Within quotes a backslash escapes a double quote or a backslash only, so regular
+// expressions can be given as is.
+type Filter struct {
+	source     string
+	expression expression
+}
+
+type expression interface {
+	matches(element reflect.Value) (bool, error)
+}
+
+type and []expression
+type or []expression
+
+type comparison struct {
+	field    []string
+	operator string
+	value    string
+	regex    *regexp.Regexp
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// Parses the filter without the leading question mark.
+func parseFilter(source string) (*Filter, error) {
+	parser := &filterParser{}
+	if err := parser.tokenize(source); err != nil {
+		return nil, err
+	}
+	expression, err := parser.or()
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := parser.next(); ok {
+		return nil, fmt.Errorf(`Unexpected "%s"`, token.text)
+	}
+	return &Filter{strings.TrimSpace(source), expression}, nil
+}
+
+// Returns true if the element matches the filter.
+func (filter *Filter) Matches(element reflect.Value) (bool, error) {
+	return filter.expression.matches(element)
+}
+
+func (filter *Filter) String() string {
+	return "?" + filter.source
+}
+
+func (expressions and) matches(element reflect.Value) (bool, error) {
+	for _, expression := range expressions {
+		if ok, err := expression.matches(element); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (expressions or) matches(element reflect.Value) (bool, error) {
+	for _, expression := range expressions {
+		if ok, err := expression.matches(element); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (comparison *comparison) matches(element reflect.Value) (bool, error) {
+	value := element
+	for _, name := range comparison.field {
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				// fields of missing optional objects never match
+				return false, nil
+			}
+			value = value.Elem()
+		}
+		if value.Kind() != reflect.Struct {
+			return false, fmt.Errorf(`Unknown field "%s"`, strings.Join(comparison.field, "."))
+		}
+		field, found := fieldByTag(value, name)
+		if !found {
+			return false, fmt.Errorf(`Unknown field "%s"`, strings.Join(comparison.field, "."))
+		}
+		value = field
+	}
+
+	if comparison.regex != nil {
+		return comparison.regex.MatchString(fmt.Sprint(value.Interface())), nil
+	}
+	expected, err := convert(value.Type(), comparison.value)
+	if err != nil {
+		return false, fmt.Errorf(`Field "%s" %s`, strings.Join(comparison.field, "."), err)
+	}
+	switch comparison.operator {
+	case "==":
+		return reflect.DeepEqual(value.Interface(), expected.Interface()), nil
+	case "!=":
+		return !reflect.DeepEqual(value.Interface(), expected.Interface()), nil
+	}
+	order, err := compare(value, expected)
+	if err != nil {
+		return false, fmt.Errorf(`Field "%s" %s`, strings.Join(comparison.field, "."), err)
+	}
+	switch comparison.operator {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	default:
+		return order >= 0, nil
+	}
+}
+
+// Returns -1, 0 or 1 if a is less than, equal to or greater than b. Only numbers and strings
+// can be ordered.
+func compare(a, b reflect.Value) (int, error) {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(a.Int() - b.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if a.Uint() < b.Uint() {
+			return -1, nil
+		} else if a.Uint() > b.Uint() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), nil
+	}
+	return 0, fmt.Errorf("cannot be ordered")
+}
+
+func sign(value int64) int {
+	if value < 0 {
+		return -1
+	} else if value > 0 {
+		return 1
+	}
+	return 0
+}
+
+// ------------------------------------------------------ parser
+
+type filterToken struct {
+	text string
+	// operators, parentheses and "&&" / "||" are symbols, everything else is a word
+	symbol bool
+}
+
+type filterParser struct {
+	tokens   []filterToken
+	position int
+}
+
+func (parser *filterParser) tokenize(source string) error {
+	for i := 0; i < len(source); {
+		switch c := source[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			parser.tokens = append(parser.tokens, filterToken{string(c), true})
+			i++
+		case strings.HasPrefix(source[i:], "&&") || strings.HasPrefix(source[i:], "||"):
+			parser.tokens = append(parser.tokens, filterToken{source[i : i+2], true})
+			i += 2
+		case c == '"':
+			word, length, err := unquote(source[i:])
+			if err != nil {
+				return err
+			}
+			parser.tokens = append(parser.tokens, filterToken{word, false})
+			i += length
+		default:
+			if operator := operatorAt(source[i:]); operator != "" {
+				parser.tokens = append(parser.tokens, filterToken{operator, true})
+				i += len(operator)
+				continue
+			}
+			start := i
+			for i < len(source) && !strings.ContainsRune(" \t()&|\"=!<>", rune(source[i])) {
+				i++
+			}
+			if i == start {
+				return fmt.Errorf(`Unexpected "%c"`, source[i])
+			}
+			parser.tokens = append(parser.tokens, filterToken{source[start:i], false})
+		}
+	}
+	return nil
+}
+
+func operatorAt(source string) string {
+	for _, operator := range operators {
+		if strings.HasPrefix(source, operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+// Returns the content of the quoted string at the start of source and the length of the quoted
+// string including the quotes.
+func unquote(source string) (string, int, error) {
+	var word []byte
+	for i := 1; i < len(source); i++ {
+		switch c := source[i]; {
+		case c == '\\' && i+1 < len(source) && (source[i+1] == '"' || source[i+1] == '\\'):
+			word = append(word, source[i+1])
+			i++
+		case c == '"':
+			return string(word), i + 1, nil
+		default:
+			word = append(word, c)
+		}
+	}
+	return "", 0, fmt.Errorf("Missing closing quote")
+}
+
+func (parser *filterParser) next() (filterToken, bool) {
+	if parser.position >= len(parser.tokens) {
+		return filterToken{}, false
+	}
+	token := parser.tokens[parser.position]
+	parser.position++
+	return token, true
+}
+
+func (parser *filterParser) accept(symbol string) bool {
+	if parser.position < len(parser.tokens) {
+		token := parser.tokens[parser.position]
+		if token.symbol && token.text == symbol {
+			parser.position++
+			return true
+		}
+	}
+	return false
+}
+
+func (parser *filterParser) or() (expression, error) {
+	var expressions or
+	for {
+		expression, err := parser.and()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+		if !parser.accept("||") {
+			break
+		}
+	}
+	if len(expressions) == 1 {
+		return expressions[0], nil
+	}
+	return expressions, nil
+}
+
+func (parser *filterParser) and() (expression, error) {
+	var expressions and
+	for {
+		expression, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+		if !parser.accept("&&") {
+			break
+		}
+	}
+	if len(expressions) == 1 {
+		return expressions[0], nil
+	}
+	return expressions, nil
+}
+
+func (parser *filterParser) comparison() (expression, error) {
+	if parser.accept("(") {
+		expression, err := parser.or()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.accept(")") {
+			return nil, fmt.Errorf(`Missing ")"`)
+		}
+		return expression, nil
+	}
+
+	field, ok := parser.next()
+	if !ok {
+		return nil, fmt.Errorf("Missing field")
+	}
+	if field.symbol {
+		return nil, fmt.Errorf(`Expected field, got "%s"`, field.text)
+	}
+	operator, ok := parser.next()
+	if !ok || !operator.symbol || operatorAt(operator.text) != operator.text {
+		return nil, fmt.Errorf(`Missing operator after "%s"`, field.text)
+	}
+	value, ok := parser.next()
+	if !ok || value.symbol {
+		return nil, fmt.Errorf(`Missing value after "%s%s"`, field.text, operator.text)
+	}
+
+	comparison := &comparison{field: strings.Split(field.text, "."), operator: operator.text, value: value.text}
+	if operator.text == "=~" {
+		regex, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, err
+		}
+		comparison.regex = regex
+	}
+	return comparison, nil
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/hpehl/whatunga/model"
-	"github.com/oleiade/reflections"
+	"math"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -16,13 +16,16 @@ const (
 	PlainSegment SegmentKind = iota
 	IndexSegment
 	RangeSegment
+	DescentSegment
 
 	// IndexKind
 	NumericIndex IndexKind = iota
 	AlphaNumericIndex
+	GlobIndex
+	FilterIndex
 
-	// Undefined range
-	Undefined int = -1
+	// Undefined range bound or step. Negative bounds count from the end.
+	Undefined int = math.MinInt32
 )
 
 type SegmentKind int
@@ -43,14 +46,22 @@ type Index struct {
 	Value interface{}
 }
 
+// A range like "[1:]" or "[::2]" or a union like "[0,2,web-1]". The indices of a union are
+// numeric or alpha-numeric. If the union is given, From, To and Step are undefined.
 type Range struct {
-	From, To int
+	From, To, Step int
+	Union          []Index
 }
 
 // regular expression to distinguish between the different segments
 var plainSegment = regexp.MustCompile(`^([\w-]+)$`)
-var indexSegment = regexp.MustCompile(`^([\w-]+)\[((\d+)|([A-Za-z0-9_-]+))\]$`)
-var rangeSegment = regexp.MustCompile(`^([\w-]+)\[((\d*)(:)(\d*))\]$`)
+var indexSegment = regexp.MustCompile(`^([\w-]+)\[((-?\d+)|([A-Za-z0-9_-]+)|([A-Za-z0-9_*?-]+))\]$`)
+var rangeSegment = regexp.MustCompile(`^([\w-]+)\[((-?\d*):(-?\d*)(?::(-?\d*))?)\]$`)
+var quotedSegment = regexp.MustCompile(`^([\w-]+)\[("(?:[^"\\]|\\.)*")\]$`)
+var unionSegment = regexp.MustCompile(`^([\w-]+)\[(.*,.*)\]$`)
+var numericIndex = regexp.MustCompile(`^-?\d+$`)
+var plainName = regexp.MustCompile(`^[\w-]+$`)
+var filterSegment = regexp.MustCompile(`^([\w-]+)\[\?(.*)\]$`)
 
 // the current path which is used by the commands and the shell
 var CurrentPath Path = []Segment{}
@@ -58,36 +69,77 @@ var LastPath Path
 
 // ------------------------------------------------------ path functions
 
-// Turns a string into a path
+// Turns a string into a path. Both "**" and an empty segment as in "..jvm" are turned into a
+// descent segment.
 func Parse(p string) (Path, error) {
 	if p == "" {
 		return make(Path, 0), nil
 	}
 
 	var path Path
-	segments := strings.Split(p, ".")
-	for _, s := range segments {
-		segment := Segment{"", PlainSegment, Index{}, Range{Undefined, Undefined}}
+	segments := splitSegments(p)
+	for i, s := range segments {
+		segment := Segment{"", PlainSegment, Index{}, Range{Undefined, Undefined, Undefined, nil}}
 
-		if s != "" {
+		if s == "" || s == "**" {
+			if i == len(segments)-1 {
+				return nil, fmt.Errorf(`Missing segment after descent in path "%s"`, p)
+			}
+			if len(path) != 0 && path[len(path)-1].Kind == DescentSegment {
+				// ".." and "**.**" are the same as a single descent
+				continue
+			}
+			segment.Kind = DescentSegment
+
+		} else {
 			// check most specific re first!
-			if rangeSegment.MatchString(s) {
+			if filterSegment.MatchString(s) {
+				groups := filterSegment.FindStringSubmatch(s)
+				filter, err := parseFilter(groups[2])
+				if err != nil {
+					return nil, fmt.Errorf(`Invalid filter in segment "%s" of path "%s": %s.`, s, p, err)
+				}
+				segment.Name = groups[1]
+				segment.Kind = IndexSegment
+				segment.Index.Kind = FilterIndex
+				segment.Index.Value = filter
+
+			} else if rangeSegment.MatchString(s) {
 				groups := rangeSegment.FindStringSubmatch(s)
 				segment.Name = groups[1]
 				segment.Kind = RangeSegment
-				if groups[3] != "" {
-					from, err := strconv.Atoi(groups[3])
-					if err != nil {
-						return nil, fmt.Errorf(`Unable to resolve path "%s": "%s:%s" is not a valid range`, p, groups[3], groups[5])
+				for i, bound := range []*int{&segment.Range.From, &segment.Range.To, &segment.Range.Step} {
+					if groups[i+3] != "" {
+						value, err := strconv.Atoi(groups[i+3])
+						if err != nil {
+							return nil, fmt.Errorf(`Unable to resolve path "%s": "%s" is not a valid range`, p, groups[2])
+						}
+						*bound = value
 					}
-					segment.Range.From = from
 				}
-				if groups[5] != "" {
-					to, err := strconv.Atoi(groups[5])
+				if segment.Range.Step != Undefined && segment.Range.Step <= 0 {
+					return nil, fmt.Errorf(`Unable to resolve path "%s": The step of range "%s" must be greater than zero`, p, groups[2])
+				}
+
+			} else if quotedSegment.MatchString(s) {
+				// quoted name: the regex ensures that the quotes are balanced
+				groups := quotedSegment.FindStringSubmatch(s)
+				name, _, _ := unquote(groups[2])
+				segment.Name = groups[1]
+				segment.Kind = IndexSegment
+				segment.Index.Kind = AlphaNumericIndex
+				segment.Index.Value = name
+
+			} else if unionSegment.MatchString(s) {
+				groups := unionSegment.FindStringSubmatch(s)
+				segment.Name = groups[1]
+				segment.Kind = RangeSegment
+				for _, item := range splitUnion(groups[2]) {
+					index, err := unionIndex(strings.TrimSpace(item))
 					if err != nil {
-						return nil, fmt.Errorf(`Unable to resolve path "%s": "%s:%s" is not a valid range`, p, groups[3], groups[5])
+						return nil, fmt.Errorf(`Invalid index "%s" in segment "%s" of path "%s"`, strings.TrimSpace(item), s, p)
 					}
-					segment.Range.To = to
+					segment.Range.Union = append(segment.Range.Union, index)
 				}
 
 			} else if indexSegment.MatchString(s) {
@@ -106,6 +158,10 @@ func Parse(p string) (Path, error) {
 					// alpha-numeric range
 					segment.Index.Kind = AlphaNumericIndex
 					segment.Index.Value = groups[4]
+				} else if groups[5] != "" {
+					// glob: "*" matches any sequence, "?" any single character
+					segment.Index.Kind = GlobIndex
+					segment.Index.Value = groups[5]
 				}
 
 			} else if plainSegment.MatchString(s) {
@@ -122,9 +178,88 @@ func Parse(p string) (Path, error) {
 	return path, nil
 }
 
+// Splits the path at dots which are not part of an index. Within an index dots can be used in
+// quoted strings and filters.
+func splitSegments(p string) []string {
+	var segments []string
+	var depth, start int
+	var quoted, escaped bool
+	for i, c := range p {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '.' && depth == 0:
+			segments = append(segments, p[start:i])
+			start = i + 1
+		}
+	}
+	return append(segments, p[start:])
+}
+
+// Splits the indices of a union at commas which are not part of a quoted name.
+func splitUnion(union string) []string {
+	var items []string
+	var start int
+	var quoted, escaped bool
+	for i, c := range union {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			items = append(items, union[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, union[start:])
+}
+
+// Parses one index of a union which is either numeric, a plain or a quoted name.
+func unionIndex(item string) (Index, error) {
+	switch {
+	case numericIndex.MatchString(item):
+		index, err := strconv.Atoi(item)
+		if err != nil {
+			return Index{}, err
+		}
+		return Index{NumericIndex, index}, nil
+	case plainName.MatchString(item):
+		return Index{AlphaNumericIndex, item}, nil
+	case strings.HasPrefix(item, `"`):
+		name, length, err := unquote(item)
+		if err == nil && length == len(item) {
+			return Index{AlphaNumericIndex, name}, nil
+		}
+	}
+	return Index{}, fmt.Errorf("Invalid index")
+}
+
+// Returns the name as used in a named index like "servers[web-1]". Names which contain other
+// characters than letters, digits, "_" and "-" or which look like a numeric index are enclosed in
+// double quotes. Within the quotes double quotes and backslashes are escaped with a backslash.
+func QuoteName(name string) string {
+	if plainName.MatchString(name) && !numericIndex.MatchString(name) {
+		return name
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
 func SplitLastSegment(arg string) (string, string) {
 	var path, segment string
-	lastDot := strings.LastIndex(arg, ".")
+	segments := splitSegments(arg)
+	lastDot := len(arg) - len(segments[len(segments)-1]) - 1
 	if lastDot != -1 {
 		path = arg[0:lastDot]
 		segment = arg[lastDot+1:]
@@ -137,11 +272,20 @@ func SplitLastSegment(arg string) (string, string) {
 
 func LastOpenSquareBracket(segment string) (bool, string, string) {
 	var counter, pos int
+	var quoted, escaped bool
 	for index, c := range segment {
-		if c == '[' {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
 			counter++
 			pos = index
-		} else if c == ']' {
+		case c == ']':
 			counter--
 		}
 	}
@@ -161,101 +305,21 @@ func LastOpenSquareBracket(segment string) (bool, string, string) {
 // Get the value of the project model the given path points to. The path must be unambiguous,
 // thus it must not contain ranges.
 func (path Path) Resolve(project *model.Project) (interface{}, error) {
-	var context interface{} = project
-
-	for _, segment := range path {
-
-		// Find field referenced by the tag <segment.Name>
-		tags, err := reflections.Tags(context, "json")
-		if err != nil {
-			return nil, fmt.Errorf(`Unable to resolve path "%s": %s.`, path, err)
-		}
-		var fieldName = ""
-		for name, tag := range tags {
-			if tag == segment.Name {
-				fieldName = name
-				break
-			}
-		}
-		if fieldName == "" {
-			return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" not found.`, path, segment)
-		}
-
-		// Check the field type
-		kind, err := reflections.GetFieldKind(context, fieldName)
-		if err != nil {
-			return nil, fmt.Errorf(`Unable to resolve path "%s": %s.`, path, err)
-		}
-
-		switch kind {
-		case reflect.Struct:
-			if segment.Kind == IndexSegment || segment.Kind == RangeSegment {
-				return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" does not refer to a collection.`, path, segment)
-			}
-			nested, err := reflections.GetField(context, fieldName)
-			if err != nil {
-				return nil, fmt.Errorf(`Unable to resolve path "%s": %s.`, path, err)
-			}
-			context = nested
-
-		case reflect.Slice:
-			switch segment.Kind {
-
-			case PlainSegment:
-				return nil, fmt.Errorf(`Unable to resolve path "%s": Missig index given for collection "%s".`, path, segment)
-
-			case IndexSegment:
-				slice, err := reflections.GetField(context, fieldName)
-				if err != nil {
-					return nil, fmt.Errorf(`Unable to resolve path "%s": %s.`, path, err)
-				}
-				sliceValue := reflect.ValueOf(slice)
-
-				if segment.Index.Kind == NumericIndex {
-					var index = segment.Index.Value.(int)
-					if index < 0 || index >= sliceValue.Len() {
-						return nil, fmt.Errorf(`Unable to resolve path "%s": Index in segment "%s" is out of bounds.`, path, segment)
-					}
-					context = sliceValue.Index(index).Interface()
-
-				} else if segment.Index.Kind == AlphaNumericIndex {
-					var indexFound = false
-					for i := 0; i < sliceValue.Len(); i++ {
-						element := sliceValue.Index(i).Interface()
-						if exists, _ := reflections.HasField(element, "Name"); exists {
-							name, _ := reflections.GetField(element, "Name")
-							if segment.Index.Value == name {
-								indexFound = true
-								context = element
-								break
-							}
-						}
-					}
-					if !indexFound {
-						return nil, fmt.Errorf(`Unable to resolve path "%s": Named index in segment "%s" not found.`, path, segment)
-					}
-				}
-
-			case RangeSegment:
-				return nil, fmt.Errorf(`Unable to resolve path "%s": Range in segment "%s" not supported.`, path, segment)
-			}
-
-		default:
-			if segment.Kind != PlainSegment {
-				return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" does refer to a collection.`, path, segment)
-			}
-			nested, err := reflections.GetField(context, fieldName)
-			if err != nil {
-				return nil, fmt.Errorf(`Unable to resolve path "%s": %s.`, path, err)
-			}
-			context = nested
-		}
+	value, err := path.resolveValue(project)
+	if err != nil {
+		return nil, err
+	}
+	return value.Interface(), nil
+}
 
-		if context == nil {
-			return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" not found.`, path, segment)
-		}
+// Walks the project model along the path and returns the addressable value the path points to.
+// Contrary to Resolve() the returned value can be used to modify the project model.
+func (path Path) resolveValue(project *model.Project) (reflect.Value, error) {
+	targets, err := path.walk(project, false)
+	if err != nil {
+		return reflect.Value{}, err
 	}
-	return context, nil
+	return targets[0].Value, nil
 }
 
 // Append the specified path to this path and return the result as a new path
@@ -298,16 +362,37 @@ func (segment Segment) String() string {
 	switch segment.Kind {
 	case RangeSegment:
 		buffer.WriteString("[")
-		if segment.Range.From != Undefined {
-			buffer.WriteString(fmt.Sprintf("%d", segment.Range.From))
-		}
-		buffer.WriteString(":")
-		if segment.Range.To != Undefined {
-			buffer.WriteString(fmt.Sprintf("%d", segment.Range.To))
+		if len(segment.Range.Union) != 0 {
+			for i, index := range segment.Range.Union {
+				if i > 0 {
+					buffer.WriteString(",")
+				}
+				buffer.WriteString(indexString(index))
+			}
+		} else {
+			if segment.Range.From != Undefined {
+				buffer.WriteString(fmt.Sprintf("%d", segment.Range.From))
+			}
+			buffer.WriteString(":")
+			if segment.Range.To != Undefined {
+				buffer.WriteString(fmt.Sprintf("%d", segment.Range.To))
+			}
+			if segment.Range.Step != Undefined {
+				buffer.WriteString(fmt.Sprintf(":%d", segment.Range.Step))
+			}
 		}
 		buffer.WriteString("]")
 	case IndexSegment:
-		buffer.WriteString(fmt.Sprintf("[%v]", segment.Index.Value))
+		buffer.WriteString("[" + indexString(segment.Index) + "]")
+	case DescentSegment:
+		buffer.WriteString("**")
 	}
 	return buffer.String()
 }
+
+func indexString(index Index) string {
+	if index.Kind == AlphaNumericIndex {
+		return QuoteName(index.Value.(string))
+	}
+	return fmt.Sprint(index.Value)
+}
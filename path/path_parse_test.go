@@ -11,7 +11,7 @@ type PathParseSuite struct {
 
 func (s *PathParseSuite) SetUpSuite(_ *C) {
 	s.emptyIndex = Index{}
-	s.emptyRange = Range{Undefined, Undefined}
+	s.emptyRange = Range{Undefined, Undefined, Undefined, nil}
 }
 
 var _ = Suite(&PathParseSuite{})
@@ -48,28 +48,142 @@ func (s *PathParseSuite) TestParseAlphaNumericIndex(c *C) {
 	assertSegment(c, path[0], "bar", IndexSegment, Index{AlphaNumericIndex, "f0o"}, s.emptyRange)
 }
 
+func (s *PathParseSuite) TestParseGlobIndex(c *C) {
+	path, err := Parse("foo[web-*].bar[slave?]")
+	assertPath(c, path, err, 2)
+	assertSegment(c, path[0], "foo", IndexSegment, Index{GlobIndex, "web-*"}, s.emptyRange)
+	assertSegment(c, path[1], "bar", IndexSegment, Index{GlobIndex, "slave?"}, s.emptyRange)
+	c.Assert(path.String(), Equals, "foo[web-*].bar[slave?]")
+}
+
+func (s *PathParseSuite) TestParseFilter(c *C) {
+	path, err := Parse(`hosts[:].servers[?server-group=="prod.eu" && (auto-start==true || port-offset>=100)].name`)
+	assertPath(c, path, err, 3)
+	c.Assert(path[1].Name, Equals, "servers")
+	c.Assert(path[1].Kind, Equals, IndexSegment)
+	c.Assert(path[1].Index.Kind, Equals, FilterIndex)
+	c.Assert(path.String(), Equals, `hosts[:].servers[?server-group=="prod.eu" && (auto-start==true || port-offset>=100)].name`)
+}
+
+func (s *PathParseSuite) TestParseDescent(c *C) {
+	for _, p := range []string{"**.jvm", "..jvm", "**.**.jvm", "**..jvm"} {
+		path, err := Parse(p)
+		assertPath(c, path, err, 2)
+		assertSegment(c, path[0], "", DescentSegment, s.emptyIndex, s.emptyRange)
+		assertSegment(c, path[1], "jvm", PlainSegment, s.emptyIndex, s.emptyRange)
+		c.Assert(path.String(), Equals, "**.jvm")
+	}
+}
+
+func (s *PathParseSuite) TestParseNestedDescent(c *C) {
+	path, err := Parse("hosts[0]..port-offset")
+	assertPath(c, path, err, 3)
+	assertSegment(c, path[0], "hosts", IndexSegment, Index{NumericIndex, 0}, s.emptyRange)
+	assertSegment(c, path[1], "", DescentSegment, s.emptyIndex, s.emptyRange)
+	assertSegment(c, path[2], "port-offset", PlainSegment, s.emptyIndex, s.emptyRange)
+	c.Assert(path.String(), Equals, "hosts[0].**.port-offset")
+}
+
 func (s *PathParseSuite) TestParseSliceRangeFrom(c *C) {
 	path, err := Parse("foo[42:]")
 	assertPath(c, path, err, 1)
-	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{42, Undefined})
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{42, Undefined, Undefined, nil})
 }
 
 func (s *PathParseSuite) TestParseSliceRangeTo(c *C) {
 	path, err := Parse("foo[:42]")
 	assertPath(c, path, err, 1)
-	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, 42})
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, 42, Undefined, nil})
 }
 
 func (s *PathParseSuite) TestParseSliceRangeFromTo(c *C) {
 	path, err := Parse("foo[23:42]")
 	assertPath(c, path, err, 1)
-	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{23, 42})
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{23, 42, Undefined, nil})
 }
 
 func (s *PathParseSuite) TestParseSliceRangeAll(c *C) {
 	path, err := Parse("foo[:]")
 	assertPath(c, path, err, 1)
-	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, Undefined})
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, Undefined, Undefined, nil})
+}
+
+func (s *PathParseSuite) TestParseSliceRangeNegative(c *C) {
+	path, err := Parse("foo[-3:-1]")
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{-3, -1, Undefined, nil})
+}
+
+func (s *PathParseSuite) TestParseSliceRangeStep(c *C) {
+	path, err := Parse("foo[::2]")
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, Undefined, 2, nil})
+
+	path, err = Parse("foo[1:-1:3]")
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{1, -1, 3, nil})
+}
+
+func (s *PathParseSuite) TestParseNegativeIndex(c *C) {
+	path, err := Parse("foo[-1]")
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", IndexSegment, Index{NumericIndex, -1}, s.emptyRange)
+}
+
+func (s *PathParseSuite) TestParseUnion(c *C) {
+	path, err := Parse("foo[0, 2,web-1,-1]")
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, Undefined, Undefined, []Index{
+		Index{NumericIndex, 0}, Index{NumericIndex, 2}, Index{AlphaNumericIndex, "web-1"}, Index{NumericIndex, -1}}})
+	c.Assert(path.String(), Equals, "foo[0,2,web-1,-1]")
+}
+
+func (s *PathParseSuite) TestParseQuotedName(c *C) {
+	path, err := Parse(`server-groups["app.v2"].deployments["ticketmonster.ear"].name`)
+	assertPath(c, path, err, 3)
+	assertSegment(c, path[0], "server-groups", IndexSegment, Index{AlphaNumericIndex, "app.v2"}, s.emptyRange)
+	assertSegment(c, path[1], "deployments", IndexSegment, Index{AlphaNumericIndex, "ticketmonster.ear"}, s.emptyRange)
+	assertSegment(c, path[2], "name", PlainSegment, s.emptyIndex, s.emptyRange)
+
+	path, err = Parse(`foo["a \"quoted\" [name], with \\ and \n"]`)
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", IndexSegment, Index{AlphaNumericIndex, `a "quoted" [name], with \ and \n`}, s.emptyRange)
+
+	// quoted names are never numeric indices or globs
+	path, err = Parse(`foo["42"].bar["web-*"]`)
+	assertPath(c, path, err, 2)
+	assertSegment(c, path[0], "foo", IndexSegment, Index{AlphaNumericIndex, "42"}, s.emptyRange)
+	assertSegment(c, path[1], "bar", IndexSegment, Index{AlphaNumericIndex, "web-*"}, s.emptyRange)
+}
+
+func (s *PathParseSuite) TestParseQuotedUnion(c *C) {
+	path, err := Parse(`foo[0, "a,b", "c.d",e]`)
+	assertPath(c, path, err, 1)
+	assertSegment(c, path[0], "foo", RangeSegment, s.emptyIndex, Range{Undefined, Undefined, Undefined, []Index{
+		Index{NumericIndex, 0}, Index{AlphaNumericIndex, "a,b"}, Index{AlphaNumericIndex, "c.d"}, Index{AlphaNumericIndex, "e"}}})
+	c.Assert(path.String(), Equals, `foo[0,"a,b","c.d",e]`)
+}
+
+func (s *PathParseSuite) TestQuoteName(c *C) {
+	c.Assert(QuoteName("web-1"), Equals, "web-1")
+	c.Assert(QuoteName("app.v2"), Equals, `"app.v2"`)
+	c.Assert(QuoteName("42"), Equals, `"42"`)
+	c.Assert(QuoteName(""), Equals, `""`)
+	c.Assert(QuoteName(`a "b" \c`), Equals, `"a \"b\" \\c"`)
+}
+
+func (s *PathParseSuite) TestParseRoundTrip(c *C) {
+	for _, p := range []string{
+		"foo", "foo[0]", "foo[-1]", "foo[bar]", "foo[web-*]",
+		"foo[:]", "foo[1:]", "foo[:2]", "foo[1:2]", "foo[-2:]", "foo[:-1]", "foo[::2]", "foo[1::2]", "foo[-4:-1:2]",
+		"foo[0,2]", "foo[0,-1,bar]", "foo[bar,baz]",
+		`foo["a.b"]`, `foo["-1"]`, `foo["a \"b\" \\c"]`, `foo["x,y","1",z]`,
+		"a[0].b[1:].c[::2].d[e,f].**.g",
+	} {
+		path, err := Parse(p)
+		c.Assert(err, IsNil)
+		c.Assert(path.String(), Equals, p)
+	}
 }
 
 func (s *PathParseSuite) TestParseMixed(c *C) {
@@ -77,10 +191,10 @@ func (s *PathParseSuite) TestParseMixed(c *C) {
 	assertPath(c, path, err, 7)
 	assertSegment(c, path[0], "a", IndexSegment, Index{NumericIndex, 0}, s.emptyRange)
 	assertSegment(c, path[1], "b", IndexSegment, Index{AlphaNumericIndex, "z"}, s.emptyRange)
-	assertSegment(c, path[2], "c", RangeSegment, s.emptyIndex, Range{1, Undefined})
-	assertSegment(c, path[3], "d", RangeSegment, s.emptyIndex, Range{Undefined, 2})
-	assertSegment(c, path[4], "e", RangeSegment, s.emptyIndex, Range{3, 4})
-	assertSegment(c, path[5], "f", RangeSegment, s.emptyIndex, Range{Undefined, Undefined})
+	assertSegment(c, path[2], "c", RangeSegment, s.emptyIndex, Range{1, Undefined, Undefined, nil})
+	assertSegment(c, path[3], "d", RangeSegment, s.emptyIndex, Range{Undefined, 2, Undefined, nil})
+	assertSegment(c, path[4], "e", RangeSegment, s.emptyIndex, Range{3, 4, Undefined, nil})
+	assertSegment(c, path[5], "f", RangeSegment, s.emptyIndex, Range{Undefined, Undefined, Undefined, nil})
 	assertSegment(c, path[6], "g", PlainSegment, s.emptyIndex, s.emptyRange)
 }
 
@@ -92,6 +206,41 @@ func (s *PathParseSuite) TestParseMalformed(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *PathParseSuite) TestParseTrailingDescent(c *C) {
+	for _, p := range []string{"**", "hosts[0].**", "hosts[0]."} {
+		path, err := Parse(p)
+		c.Assert(path, IsNil)
+		c.Assert(err, ErrorMatches, `Missing segment after descent in path ".*"`)
+	}
+}
+
+func (s *PathParseSuite) TestParseInvalidQuotedName(c *C) {
+	for _, p := range []string{`foo["bar]`, `foo["bar"baz"]`, `foo["bar].baz`} {
+		path, err := Parse(p)
+		c.Assert(path, IsNil)
+		c.Assert(err, NotNil)
+	}
+	path, err := Parse(`foo[0,"bar]`)
+	c.Assert(path, IsNil)
+	c.Assert(err, ErrorMatches, `Invalid index "\"bar" in segment ".*" of path ".*"`)
+}
+
+func (s *PathParseSuite) TestParseInvalidStep(c *C) {
+	for _, p := range []string{"foo[::0]", "foo[1:2:-1]"} {
+		path, err := Parse(p)
+		c.Assert(path, IsNil)
+		c.Assert(err, ErrorMatches, `Unable to resolve path ".*": The step of range ".*" must be greater than zero`)
+	}
+}
+
+func (s *PathParseSuite) TestParseInvalidFilter(c *C) {
+	for _, filter := range []string{"?", "?name", "?name==", `?name=="foo`, "?name==foo &&", "?(name==foo", "?name=~(", "?==foo"} {
+		path, err := Parse("servers[" + filter + "]")
+		c.Assert(path, IsNil)
+		c.Assert(err, ErrorMatches, `Invalid filter in segment "servers\[.*\]" of path ".*": .*\.`)
+	}
+}
+
 // ------------------------------------------------------ helper functions
 
 func assertPath(c *C, path Path, err error, length int) {
@@ -108,5 +257,5 @@ func assertSegment(c *C, segment Segment, name string, segmentKind SegmentKind,
 	c.Assert(segment.Name, Equals, name)
 	c.Assert(segment.Kind, Equals, segmentKind)
 	c.Assert(segment.Index, Equals, index)
-	c.Assert(segment.Range, Equals, rng)
+	c.Assert(segment.Range, DeepEquals, rng)
 }
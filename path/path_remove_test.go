@@ -0,0 +1,101 @@
+package path
+
+import (
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+)
+
+// ------------------------------------------------------ setup
+
+type PathRemoveSuite struct {
+	project *model.Project
+}
+
+func (s *PathRemoveSuite) SetUpTest(_ *C) {
+	s.project = &model.Project{
+		Name: "test",
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "server-group0", Jvm: &model.Jvm{Name: "jvm0"}},
+			model.ServerGroup{Name: "server-group1"},
+		},
+		Hosts: []model.Host{
+			model.Host{
+				Name: "master",
+				Servers: []model.Server{
+					model.Server{Name: "server0"},
+					model.Server{Name: "server1"},
+					model.Server{Name: "server2"},
+					model.Server{Name: "server3"},
+				},
+			},
+			model.Host{
+				Name: "slave",
+				Servers: []model.Server{
+					model.Server{Name: "server4"},
+					model.Server{Name: "server5"},
+				},
+			},
+		},
+	}
+}
+
+var _ = Suite(&PathRemoveSuite{})
+
+// ------------------------------------------------------ remove tests
+
+func (s *PathRemoveSuite) TestRemoveElement(c *C) {
+	assertRemove(c, s.project, "hosts[master].servers[server1]")
+	c.Assert(serverNames(s.project.Hosts[0]), DeepEquals, []string{"server0", "server2", "server3"})
+}
+
+func (s *PathRemoveSuite) TestRemoveRange(c *C) {
+	assertRemove(c, s.project, "hosts[master].servers[1:3]")
+	c.Assert(serverNames(s.project.Hosts[0]), DeepEquals, []string{"server0", "server3"})
+}
+
+func (s *PathRemoveSuite) TestRemoveNestedRange(c *C) {
+	assertRemove(c, s.project, "hosts[:].servers[1:]")
+	c.Assert(serverNames(s.project.Hosts[0]), DeepEquals, []string{"server0"})
+	c.Assert(serverNames(s.project.Hosts[1]), DeepEquals, []string{"server4"})
+}
+
+func (s *PathRemoveSuite) TestRemoveAll(c *C) {
+	assertRemove(c, s.project, "hosts[:]")
+	c.Assert(len(s.project.Hosts), Equals, 0)
+}
+
+func (s *PathRemoveSuite) TestRemovePointer(c *C) {
+	assertRemove(c, s.project, "server-groups[server-group0].jvm")
+	c.Assert(s.project.ServerGroups[0].Jvm, IsNil)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *PathRemoveSuite) TestRemoveAttribute(c *C) {
+	path, _ := Parse("hosts[:].name")
+	targets, err := path.ResolveAll(s.project)
+	c.Assert(err, IsNil)
+
+	err = Remove(targets)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Unable to remove "hosts[master].name": Only elements of a collection and optional objects can be removed.`)
+	c.Assert(len(s.project.Hosts), Equals, 2)
+}
+
+// ------------------------------------------------------ helper functions
+
+func assertRemove(c *C, project *model.Project, p string) {
+	path, err := Parse(p)
+	c.Assert(err, IsNil)
+	targets, err := path.ResolveAll(project)
+	c.Assert(err, IsNil)
+	c.Assert(Remove(targets), IsNil)
+}
+
+func serverNames(host model.Host) []string {
+	var names []string
+	for _, server := range host.Servers {
+		names = append(names, server.Name)
+	}
+	return names
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/hpehl/whatunga/model"
 	. "gopkg.in/check.v1"
 	"reflect"
+	"regexp"
 )
 
 // ------------------------------------------------------ setup
@@ -183,6 +184,233 @@ func (s *PathResolveSuite) TestResolveAlphaNumericIndex(c *C) {
 	assertField(c, value, err, 100)
 }
 
+func (s *PathResolveSuite) TestResolveNegativeIndex(c *C) {
+	path, _ := Parse("hosts[-2].servers[-1].port-offset")
+	value, err := path.Resolve(s.project)
+
+	assertField(c, value, err, 100)
+}
+
+func (s *PathResolveSuite) TestResolveAllRange(c *C) {
+	path, _ := Parse("hosts[:].name")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host0].name", "hosts[host1].name", "hosts[host2].name")
+	c.Assert(targets[1].Value.Interface(), Equals, "host1")
+}
+
+func (s *PathResolveSuite) TestResolveAllNestedRanges(c *C) {
+	path, _ := Parse("hosts[1:].servers[1:].port-offset")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err,
+		"hosts[host1].servers[host1-server1].port-offset",
+		"hosts[host1].servers[host1-server2].port-offset",
+		"hosts[host2].servers[1].port-offset",
+		"hosts[host2].servers[2].port-offset")
+	c.Assert(targets[3].Value.Interface(), Equals, 3)
+}
+
+func (s *PathResolveSuite) TestResolveAllNegativeRange(c *C) {
+	path, _ := Parse("hosts[-2:].servers[:-2].name")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host1].servers[host1-server0].name", "hosts[host2].servers[0].name")
+}
+
+func (s *PathResolveSuite) TestResolveAllStep(c *C) {
+	path, _ := Parse("hosts[::2].servers[1::2]")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host0].servers[host0-server1]", "hosts[host2].servers[1]")
+}
+
+func (s *PathResolveSuite) TestResolveAllUnion(c *C) {
+	path, _ := Parse("hosts[host1].servers[2,host1-server0,-1].port-offset")
+	targets, err := path.ResolveAll(s.project)
+
+	// model order, no duplicates
+	assertTargets(c, targets, err,
+		"hosts[host1].servers[host1-server0].port-offset",
+		"hosts[host1].servers[host1-server2].port-offset")
+	c.Assert(path.IsMulti(), Equals, true)
+}
+
+func (s *PathResolveSuite) TestResolveAllCanonical(c *C) {
+	path, _ := Parse("hosts[0].servers[0:2]")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host0].servers[host0-server0]", "hosts[host0].servers[host0-server1]")
+}
+
+func (s *PathResolveSuite) TestResolveAllWithoutRange(c *C) {
+	path, _ := Parse("config.domain-user.username")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "config.domain-user.username")
+	c.Assert(targets[0].Value.Interface(), Equals, "dc")
+}
+
+func (s *PathResolveSuite) TestResolveAllEmptyRange(c *C) {
+	path, _ := Parse("server-groups[server-group1].deployments[:]")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err)
+}
+
+func (s *PathResolveSuite) TestResolveAllRangeBeyondBounds(c *C) {
+	project := &model.Project{
+		Hosts: []model.Host{
+			model.Host{Name: "master", DC: true, Servers: []model.Server{}},
+			model.Host{Name: "slave", Servers: []model.Server{
+				model.Server{Name: "server0"},
+				model.Server{Name: "server1"},
+				model.Server{Name: "server2"},
+			}},
+		},
+	}
+
+	path, _ := Parse("hosts[:].servers[1:]")
+	targets, err := path.ResolveAll(project)
+	assertTargets(c, targets, err, "hosts[slave].servers[server1]", "hosts[slave].servers[server2]")
+
+	path, _ = Parse("hosts[:].servers[:5]")
+	targets, err = path.ResolveAll(project)
+	assertTargets(c, targets, err,
+		"hosts[slave].servers[server0]", "hosts[slave].servers[server1]", "hosts[slave].servers[server2]")
+
+	path, _ = Parse("hosts[1:5].servers[-5:-2].name")
+	targets, err = path.ResolveAll(project)
+	assertTargets(c, targets, err, "hosts[slave].servers[server0].name")
+
+	path, _ = Parse("hosts[5:].name")
+	targets, err = path.ResolveAll(project)
+	assertTargets(c, targets, err)
+}
+
+func (s *PathResolveSuite) TestResolveAllGlob(c *C) {
+	path, _ := Parse("hosts[host?].servers[*-server1].port-offset")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err,
+		"hosts[host0].servers[host0-server1].port-offset",
+		"hosts[host1].servers[host1-server1].port-offset")
+	c.Assert(path.IsMulti(), Equals, true)
+}
+
+func (s *PathResolveSuite) TestResolveAllGlobDuplicateNames(c *C) {
+	path, _ := Parse("hosts[host2].servers[f*]")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host2].servers[0]", "hosts[host2].servers[1]", "hosts[host2].servers[2]")
+}
+
+func (s *PathResolveSuite) TestResolveAllGlobNoMatch(c *C) {
+	path, _ := Parse("users[admin*]")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err)
+}
+
+func (s *PathResolveSuite) TestResolveAllFilter(c *C) {
+	path, _ := Parse(`hosts[:].servers[?server-group=="server-group0" && auto-start==true]`)
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host0].servers[host0-server1]")
+	c.Assert(path.IsMulti(), Equals, true)
+}
+
+func (s *PathResolveSuite) TestResolveAllFilterOperators(c *C) {
+	for filter, expected := range map[string][]string{
+		"port-offset>=50 && port-offset<100":                         {"hosts[host1].servers[host1-server1]"},
+		"port-offset>50 || name==host1-server0":                      {"hosts[host1].servers[host1-server0]", "hosts[host1].servers[host1-server2]"},
+		"port-offset<=0":                                             {"hosts[host1].servers[host1-server0]"},
+		"server-group!=server-group1":                                {},
+		`name=~"server[02]$"`:                                        {"hosts[host1].servers[host1-server0]", "hosts[host1].servers[host1-server2]"},
+		"(port-offset==0 || port-offset==50) && name!=host1-server0": {"hosts[host1].servers[host1-server1]"},
+	} {
+		path, _ := Parse("hosts[host1].servers[?" + filter + "]")
+		targets, err := path.ResolveAll(s.project)
+		assertTargets(c, targets, err, expected...)
+	}
+}
+
+func (s *PathResolveSuite) TestResolveAllFilterNested(c *C) {
+	path, _ := Parse(`hosts[?jvm.heap.max=="6GB"].name`)
+	targets, err := path.ResolveAll(s.project)
+
+	// hosts without JVM don't match
+	assertTargets(c, targets, err, "hosts[host1].name")
+}
+
+func (s *PathResolveSuite) TestResolveAllDescent(c *C) {
+	path, _ := Parse("**.jvm")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err,
+		"server-groups[server-group0].jvm",
+		"hosts[host0].servers[host0-server2].jvm",
+		"hosts[host1].jvm")
+	c.Assert(path.IsMulti(), Equals, true)
+	c.Assert(targets[2].Value.Interface(), Equals, s.project.Hosts[1].Jvm)
+}
+
+func (s *PathResolveSuite) TestResolveAllNestedDescent(c *C) {
+	path, _ := Parse("hosts[host1]..port-offset")
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err,
+		"hosts[host1].servers[host1-server0].port-offset",
+		"hosts[host1].servers[host1-server1].port-offset",
+		"hosts[host1].servers[host1-server2].port-offset")
+	c.Assert(targets[1].Value.Interface(), Equals, 50)
+}
+
+func (s *PathResolveSuite) TestResolveAllDescentWithIndex(c *C) {
+	path, _ := Parse(`**.servers[?jvm.name=~"-jvm$"].name`)
+	targets, err := path.ResolveAll(s.project)
+
+	assertTargets(c, targets, err, "hosts[host0].servers[host0-server2].name")
+}
+
+func (s *PathResolveSuite) TestResolveAllQuotedNames(c *C) {
+	project := &model.Project{
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "app.v2", Deployments: []model.Deployment{
+				model.Deployment{Name: "ticketmonster.ear"},
+				model.Deployment{Name: "42"},
+			}},
+		},
+	}
+	path, _ := Parse(`server-groups["app.v2"].deployments[:].name`)
+	targets, err := path.ResolveAll(project)
+
+	assertTargets(c, targets, err,
+		`server-groups["app.v2"].deployments["ticketmonster.ear"].name`,
+		`server-groups["app.v2"].deployments["42"].name`)
+
+	// canonical paths can be resolved again
+	for _, target := range targets {
+		canonical, err := Parse(target.String())
+		c.Assert(err, IsNil)
+		value, err := canonical.Resolve(project)
+		assertField(c, value, err, target.Value.Interface())
+	}
+}
+
+func (s *PathResolveSuite) TestResolveAllDescentSkipsMismatches(c *C) {
+	project := &model.Project{
+		Hosts: []model.Host{
+			model.Host{Name: "master", DC: true, Servers: []model.Server{}},
+			model.Host{Name: "slave", Servers: []model.Server{model.Server{Name: "server0"}}},
+		},
+	}
+	path, _ := Parse("**.servers[0]")
+	targets, err := path.ResolveAll(project)
+
+	assertTargets(c, targets, err, "hosts[slave].servers[server0]")
+}
+
 // ------------------------------------------------------ error tests
 
 func (s *PathResolveSuite) TestResolveUnknown(c *C) {
@@ -193,6 +421,13 @@ func (s *PathResolveSuite) TestResolveUnknown(c *C) {
 	expectError(c, value, err, `Unable to resolve path "foo": Segment "foo" not found.`)
 }
 
+func (s *PathResolveSuite) TestResolveUndefined(c *C) {
+	path, _ := Parse("hosts[host0].jvm.name")
+	value, err := path.Resolve(s.project)
+
+	expectError(c, value, err, `Unable to resolve path "hosts[host0].jvm.name": Segment "jvm" is not defined.`)
+}
+
 func (s *PathResolveSuite) TestResolveWrongKind1(c *C) {
 	emptyProject := &model.Project{}
 	path, _ := Parse("config[0].templates.-domain")
@@ -217,6 +452,42 @@ func (s *PathResolveSuite) TestResolveRange(c *C) {
 	expectError(c, value, err, `Unable to resolve path "server-groups[:].name": Range in segment "server-groups[:]" not supported.`)
 }
 
+func (s *PathResolveSuite) TestResolveGlob(c *C) {
+	path, _ := Parse("hosts[host*].name")
+	value, err := path.Resolve(s.project)
+
+	expectError(c, value, err, `Unable to resolve path "hosts[host*].name": Glob in segment "hosts[host*]" not supported.`)
+}
+
+func (s *PathResolveSuite) TestResolveFilter(c *C) {
+	path, _ := Parse("hosts[?dc==true].name")
+	value, err := path.Resolve(s.project)
+
+	expectError(c, value, err, `Unable to resolve path "hosts[?dc==true].name": Filter in segment "hosts[?dc==true]" not supported.`)
+}
+
+func (s *PathResolveSuite) TestResolveDescent(c *C) {
+	path, _ := Parse("**.jvm")
+	value, err := path.Resolve(s.project)
+
+	expectError(c, value, err, `Unable to resolve path "**.jvm": Descent "**" not supported.`)
+}
+
+func (s *PathResolveSuite) TestResolveAllFilterErrors(c *C) {
+	for filter, why := range map[string]string{
+		"foo==1":             `Unknown field "foo" in filter of segment "servers[?foo==1]".`,
+		"port-offset==abc":   `Field "port-offset" expects an integer, got "abc" in filter of segment "servers[?port-offset==abc]".`,
+		"auto-start>true":    `Field "auto-start" cannot be ordered in filter of segment "servers[?auto-start>true]".`,
+		"name.first==server": `Unknown field "name.first" in filter of segment "servers[?name.first==server]".`,
+	} {
+		path, err := Parse("hosts[host1].servers[?" + filter + "]")
+		c.Assert(err, IsNil)
+		targets, err := path.ResolveAll(s.project)
+		c.Assert(targets, IsNil)
+		c.Assert(err, ErrorMatches, `Unable to resolve path ".*": `+regexp.QuoteMeta(why))
+	}
+}
+
 func (s *PathResolveSuite) TestResolveInvalidIndex1(c *C) {
 	emptyProject := &model.Project{}
 	path, _ := Parse("hosts[0]")
@@ -239,6 +510,32 @@ func (s *PathResolveSuite) TestResolveInvalidIndex3(c *C) {
 	expectError(c, value, err, `Unable to resolve path "server-groups[foo].deployments[10]": Named index in segment "server-groups[foo]" not found.`)
 }
 
+func (s *PathResolveSuite) TestResolveNegativeOutOfBounds(c *C) {
+	path, _ := Parse("hosts[-4].name")
+	value, err := path.Resolve(s.project)
+
+	expectError(c, value, err, `Unable to resolve path "hosts[-4].name": Index in segment "hosts[-4]" is out of bounds.`)
+}
+
+func (s *PathResolveSuite) TestResolveAllUnionErrors(c *C) {
+	path, _ := Parse("hosts[0,3].name")
+	targets, err := path.ResolveAll(s.project)
+	c.Assert(targets, IsNil)
+	c.Assert(err, ErrorMatches, `Unable to resolve path "hosts\[0,3\].name": Index 3 in segment "hosts\[0,3\]" is out of bounds.`)
+
+	path, _ = Parse("hosts[host0,foo].name")
+	targets, err = path.ResolveAll(s.project)
+	c.Assert(targets, IsNil)
+	c.Assert(err, ErrorMatches, `Unable to resolve path "hosts\[host0,foo\].name": Named index "foo" in segment "hosts\[host0,foo\]" not found.`)
+}
+
+func (s *PathResolveSuite) TestResolveUnion(c *C) {
+	path, _ := Parse("hosts[0,1].name")
+	value, err := path.Resolve(s.project)
+
+	expectError(c, value, err, `Unable to resolve path "hosts[0,1].name": Range in segment "hosts[0,1]" not supported.`)
+}
+
 // ------------------------------------------------------ helper functions
 
 func assertField(c *C, value interface{}, err error, expected interface{}) {
@@ -257,3 +554,14 @@ func expectError(c *C, value interface{}, err error, why string) {
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Equals, why)
 }
+
+func assertTargets(c *C, targets []Target, err error, paths ...string) {
+	if err != nil {
+		c.Error(err)
+	}
+	c.Assert(err, IsNil)
+	c.Assert(len(targets), Equals, len(paths))
+	for i, target := range targets {
+		c.Assert(target.Path.String(), Equals, paths[i])
+	}
+}
@@ -0,0 +1,145 @@
+package path
+
+import (
+	"github.com/hpehl/whatunga/model"
+	. "gopkg.in/check.v1"
+)
+
+// ------------------------------------------------------ setup
+
+type PathSetSuite struct {
+	project *model.Project
+}
+
+func (s *PathSetSuite) SetUpTest(_ *C) {
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "server-group0"},
+		},
+		Hosts: []model.Host{
+			model.Host{
+				Name: "master",
+				Servers: []model.Server{
+					model.Server{Name: "server0", ServerGroup: "server-group0"},
+					model.Server{Name: "server1", ServerGroup: "server-group0", PortOffset: 50},
+				},
+			},
+		},
+	}
+}
+
+var _ = Suite(&PathSetSuite{})
+
+// ------------------------------------------------------ set tests
+
+func (s *PathSetSuite) TestSetString(c *C) {
+	assertSet(c, s.project, "name", "foo")
+	c.Assert(s.project.Name, Equals, "foo")
+
+	assertSet(c, s.project, "version", `"2.0 beta"`)
+	c.Assert(s.project.Version, Equals, "2.0 beta")
+}
+
+func (s *PathSetSuite) TestSetInt(c *C) {
+	assertSet(c, s.project, "hosts[master].servers[0].port-offset", "150")
+	c.Assert(s.project.Hosts[0].Servers[0].PortOffset, Equals, 150)
+}
+
+func (s *PathSetSuite) TestSetBool(c *C) {
+	assertSet(c, s.project, "hosts[0].servers[server1].auto-start", "true")
+	c.Assert(s.project.Hosts[0].Servers[1].AutoStart, Equals, true)
+}
+
+func (s *PathSetSuite) TestSetPointer(c *C) {
+	assertSet(c, s.project, "hosts[master].jvm", `{"name":"jvm0","heap":{"initial":"1GB","max":"2GB"},"options":["-server"]}`)
+	jvm := s.project.Hosts[0].Jvm
+	c.Assert(jvm, NotNil)
+	c.Assert(jvm.Name, Equals, "jvm0")
+	c.Assert(jvm.Heap.Max, Equals, "2GB")
+	c.Assert(jvm.Options, DeepEquals, []string{"-server"})
+
+	assertSet(c, s.project, "hosts[master].jvm.heap.initial", "512MB")
+	c.Assert(s.project.Hosts[0].Jvm.Heap.Initial, Equals, "512MB")
+
+	assertSet(c, s.project, "hosts[master].jvm", "null")
+	c.Assert(s.project.Hosts[0].Jvm, IsNil)
+}
+
+func (s *PathSetSuite) TestSetStruct(c *C) {
+	assertSet(c, s.project, "config.console-user", `{"username":"root","password":"secret"}`)
+	c.Assert(s.project.Config.ConsoleUser, Equals, model.User{Name: "root", Password: "secret"})
+}
+
+func (s *PathSetSuite) TestSetAll(c *C) {
+	path, _ := Parse("hosts[master].servers[:].port-offset")
+	targets, err := path.ResolveAll(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(SetAll(targets, []string{"100", "200"}), IsNil)
+	c.Assert(s.project.Hosts[0].Servers[0].PortOffset, Equals, 100)
+	c.Assert(s.project.Hosts[0].Servers[1].PortOffset, Equals, 200)
+}
+
+func (s *PathSetSuite) TestSetAllDescent(c *C) {
+	path, _ := Parse("**.port-offset")
+	targets, err := path.ResolveAll(s.project)
+	c.Assert(err, IsNil)
+	c.Assert(SetAll(targets, []string{"0", "10"}), IsNil)
+	c.Assert(s.project.Hosts[0].Servers[0].PortOffset, Equals, 0)
+	c.Assert(s.project.Hosts[0].Servers[1].PortOffset, Equals, 10)
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *PathSetSuite) TestSetInvalidInt(c *C) {
+	expectSetError(c, s.project, "hosts[master].servers[0].port-offset", "abc",
+		`Unable to set path "hosts[master].servers[0].port-offset": Segment "port-offset" expects an integer, got "abc".`)
+}
+
+func (s *PathSetSuite) TestSetInvalidBool(c *C) {
+	expectSetError(c, s.project, "hosts[master].servers[0].auto-start", "maybe",
+		`Unable to set path "hosts[master].servers[0].auto-start": Segment "auto-start" expects a boolean, got "maybe".`)
+}
+
+func (s *PathSetSuite) TestSetUnknown(c *C) {
+	expectSetError(c, s.project, "hosts[master].servers[foo].auto-start", "true",
+		`Unable to resolve path "hosts[master].servers[foo].auto-start": Named index in segment "servers[foo]" not found.`)
+}
+
+func (s *PathSetSuite) TestSetNilPointer(c *C) {
+	expectSetError(c, s.project, "hosts[master].jvm.name", "foo",
+		`Unable to resolve path "hosts[master].jvm.name": Segment "jvm" is not defined.`)
+}
+
+func (s *PathSetSuite) TestSetEmpty(c *C) {
+	expectSetError(c, s.project, "", "foo", `Unable to set path "": The project itself cannot be replaced.`)
+}
+
+func (s *PathSetSuite) TestSetAllIsAtomic(c *C) {
+	path, _ := Parse("hosts[master].servers[:].port-offset")
+	targets, err := path.ResolveAll(s.project)
+	c.Assert(err, IsNil)
+
+	err = SetAll(targets, []string{"100", "abc"})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, `Unable to set path "hosts[master].servers[server1].port-offset": Segment "port-offset" expects an integer, got "abc".`)
+	c.Assert(s.project.Hosts[0].Servers[0].PortOffset, Equals, 0)
+	c.Assert(s.project.Hosts[0].Servers[1].PortOffset, Equals, 50)
+}
+
+// ------------------------------------------------------ helper functions
+
+func assertSet(c *C, project *model.Project, p string, value string) {
+	path, err := Parse(p)
+	c.Assert(err, IsNil)
+	c.Assert(path.Set(project, value), IsNil)
+}
+
+func expectSetError(c *C, project *model.Project, p string, value string, why string) {
+	path, err := Parse(p)
+	c.Assert(err, IsNil)
+	err = path.Set(project, value)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, why)
+}
@@ -0,0 +1,39 @@
+package path
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Removes the targets from the project model. Elements of collections are removed from their
+// collection, optional objects like a JVM are set to nil. Other attributes and objects cannot
+// be removed. Either all or none of the targets are removed.
+//
+// The targets are expected in model order as returned by ResolveAll().
+func Remove(targets []Target) error {
+	for _, target := range targets {
+		if !target.IsRemovable() {
+			return fmt.Errorf(`Unable to remove "%s": Only elements of a collection and optional objects can be removed.`, target)
+		}
+	}
+
+	// remove in reverse order to keep the indices of the remaining targets valid
+	for i := len(targets) - 1; i >= 0; i-- {
+		target := targets[i]
+		if target.slice.IsValid() {
+			remaining := reflect.AppendSlice(target.slice.Slice(0, target.index), target.slice.Slice(target.index+1, target.slice.Len()))
+			target.slice.Set(remaining)
+		} else {
+			target.Value.Set(reflect.Zero(target.Value.Type()))
+		}
+	}
+	return nil
+}
+
+// Returns true if the target is an element of a collection or an optional object.
+func (target Target) IsRemovable() bool {
+	if target.slice.IsValid() {
+		return target.slice.CanSet()
+	}
+	return target.Value.Kind() == reflect.Ptr && target.Value.CanSet() && !target.Path.IsEmpty()
+}
@@ -0,0 +1,370 @@
+package path
+
+import (
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A concrete object or attribute of the project model.
+type Target struct {
+	// The canonical path of the target. It contains no ranges and uses
+	// named indices wherever the name is unique within its collection.
+	// Names are quoted if necessary.
+	Path Path
+	// The addressable value of the target
+	Value reflect.Value
+	// The collection which contains the target and the targets index in this
+	// collection. Only valid if the target is an element of a collection.
+	slice reflect.Value
+	index int
+}
+
+// Get all objects and attributes of the project model the given path points to. Ranges, globs and
+// filters are expanded in model order, nested ranges like "hosts[:].servers[1:]" lead to the cartesian
+// product. A descent like "**.jvm" matches the following segment at any depth below the preceding
+// path. Optional objects which are not defined and objects where the following segment cannot be
+// resolved are skipped by a descent.
+func (path Path) ResolveAll(project *model.Project) ([]Target, error) {
+	return path.walk(project, true)
+}
+
+// Returns true if the path contains ranges, globs, filters or descents and thus might point to
+// several targets.
+func (path Path) IsMulti() bool {
+	for _, segment := range path {
+		if segment.Kind == RangeSegment || segment.Kind == DescentSegment {
+			return true
+		}
+		if segment.Kind == IndexSegment && (segment.Index.Kind == GlobIndex || segment.Index.Kind == FilterIndex) {
+			return true
+		}
+	}
+	return false
+}
+
+func (target Target) String() string {
+	return target.Path.String()
+}
+
+// Walks the project model along the path and returns the addressable targets. If multi is false,
+// ranges, globs, filters and descents are rejected.
+func (path Path) walk(project *model.Project, multi bool) ([]Target, error) {
+	var targets = []Target{Target{Path: Path{}, Value: reflect.ValueOf(project)}}
+
+	var descent bool
+	for _, segment := range path {
+		if segment.Kind == DescentSegment {
+			if !multi {
+				return nil, fmt.Errorf(`Unable to resolve path "%s": Descent "%s" not supported.`, path, segment)
+			}
+			descent = true
+			continue
+		}
+
+		var next []Target
+		for _, target := range targets {
+			candidates := []Target{target}
+			if descent {
+				candidates = descendants(target)
+			}
+			for _, candidate := range candidates {
+				if descent && !hasField(candidate.Value, segment.Name) {
+					continue
+				}
+				expanded, err := path.step(candidate, segment, multi)
+				if err != nil {
+					if descent {
+						// the segment does not match below this candidate, e.g. an index
+						// which is out of bounds in this collection
+						continue
+					}
+					return nil, err
+				}
+				for _, target := range expanded {
+					if descent && target.Value.Kind() == reflect.Ptr && target.Value.IsNil() {
+						continue
+					}
+					next = append(next, target)
+				}
+			}
+		}
+		targets = next
+		descent = false
+	}
+	return targets, nil
+}
+
+// Returns the target itself followed by all objects and attributes below the target in model
+// order.
+func descendants(target Target) []Target {
+	result := []Target{target}
+	value := target.Value
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return result
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return result
+	}
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		field := value.Field(i)
+		segment := Segment{name, PlainSegment, Index{}, Range{Undefined, Undefined, Undefined, nil}}
+		if field.Kind() == reflect.Slice {
+			for j := 0; j < field.Len(); j++ {
+				result = append(result, descendants(element(target, segment, field, j))...)
+			}
+		} else {
+			result = append(result, descendants(Target{Path: target.Path.Append(Path{segment}), Value: field})...)
+		}
+	}
+	return result
+}
+
+// Returns true if the value is a struct or a pointer to a struct with a field for the given tag.
+func hasField(value reflect.Value, name string) bool {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return false
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return false
+	}
+	_, found := fieldByTag(value, name)
+	return found
+}
+
+// Resolves one segment relative to the given target.
+func (path Path) step(target Target, segment Segment, multi bool) ([]Target, error) {
+	var context = target.Value
+
+	// Pointers are followed as long as they're not nil
+	for context.Kind() == reflect.Ptr {
+		if context.IsNil() {
+			if len(target.Path) > 0 {
+				return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" is not defined.`, path, target.Path[len(target.Path)-1])
+			}
+			return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" not found.`, path, segment)
+		}
+		context = context.Elem()
+	}
+	if context.Kind() != reflect.Struct {
+		return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" not found.`, path, segment)
+	}
+
+	// Find field referenced by the tag <segment.Name>
+	field, found := fieldByTag(context, segment.Name)
+	if !found {
+		return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" not found.`, path, segment)
+	}
+
+	switch field.Kind() {
+	case reflect.Struct:
+		if segment.Kind == IndexSegment || segment.Kind == RangeSegment {
+			return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" does not refer to a collection.`, path, segment)
+		}
+
+	case reflect.Slice:
+		switch segment.Kind {
+
+		case PlainSegment:
+			return nil, fmt.Errorf(`Unable to resolve path "%s": Missig index given for collection "%s".`, path, segment)
+
+		case IndexSegment:
+			var index = -1
+			if segment.Index.Kind == NumericIndex {
+				index = absolute(segment.Index.Value.(int), field.Len())
+				if index < 0 || index >= field.Len() {
+					return nil, fmt.Errorf(`Unable to resolve path "%s": Index in segment "%s" is out of bounds.`, path, segment)
+				}
+			} else if segment.Index.Kind == AlphaNumericIndex {
+				index = indexOfName(field, segment.Index.Value.(string))
+				if index == -1 {
+					return nil, fmt.Errorf(`Unable to resolve path "%s": Named index in segment "%s" not found.`, path, segment)
+				}
+			} else if segment.Index.Kind == GlobIndex {
+				if !multi {
+					return nil, fmt.Errorf(`Unable to resolve path "%s": Glob in segment "%s" not supported.`, path, segment)
+				}
+				glob := globExpression(segment.Index.Value.(string))
+				var elements []Target
+				for i := 0; i < field.Len(); i++ {
+					if name, ok := nameOf(field.Index(i)); ok && glob.MatchString(name) {
+						elements = append(elements, element(target, segment, field, i))
+					}
+				}
+				return elements, nil
+			} else if segment.Index.Kind == FilterIndex {
+				if !multi {
+					return nil, fmt.Errorf(`Unable to resolve path "%s": Filter in segment "%s" not supported.`, path, segment)
+				}
+				var elements []Target
+				for i := 0; i < field.Len(); i++ {
+					matches, err := segment.Index.Value.(*Filter).Matches(field.Index(i))
+					if err != nil {
+						return nil, fmt.Errorf(`Unable to resolve path "%s": %s in filter of segment "%s".`, path, err, segment)
+					}
+					if matches {
+						elements = append(elements, element(target, segment, field, i))
+					}
+				}
+				return elements, nil
+			}
+			return []Target{element(target, segment, field, index)}, nil
+
+		case RangeSegment:
+			if !multi {
+				return nil, fmt.Errorf(`Unable to resolve path "%s": Range in segment "%s" not supported.`, path, segment)
+			}
+			if len(segment.Range.Union) != 0 {
+				return path.union(target, segment, field)
+			}
+			var from, to, step = 0, field.Len(), 1
+			if segment.Range.From != Undefined {
+				from = absolute(segment.Range.From, field.Len())
+			}
+			if segment.Range.To != Undefined {
+				to = absolute(segment.Range.To, field.Len())
+			}
+			if segment.Range.Step != Undefined {
+				step = segment.Range.Step
+			}
+			// like slices in Python a range is limited to the bounds of the collection: it
+			// selects fewer or no elements in smaller collections
+			from, to = limit(from, field.Len()), limit(to, field.Len())
+			var elements []Target
+			for i := from; i < to; i += step {
+				elements = append(elements, element(target, segment, field, i))
+			}
+			return elements, nil
+		}
+
+	default:
+		if segment.Kind != PlainSegment {
+			return nil, fmt.Errorf(`Unable to resolve path "%s": Segment "%s" does refer to a collection.`, path, segment)
+		}
+	}
+
+	plain := Segment{segment.Name, PlainSegment, Index{}, Range{Undefined, Undefined, Undefined, nil}}
+	return []Target{Target{Path: target.Path.Append(Path{plain}), Value: field}}, nil
+}
+
+// Resolves the indices of a union. The elements are returned in model order, elements which are
+// selected more than once are returned only once.
+func (path Path) union(parent Target, segment Segment, slice reflect.Value) ([]Target, error) {
+	selected := make(map[int]bool)
+	for _, index := range segment.Range.Union {
+		if index.Kind == NumericIndex {
+			i := absolute(index.Value.(int), slice.Len())
+			if i < 0 || i >= slice.Len() {
+				return nil, fmt.Errorf(`Unable to resolve path "%s": Index %d in segment "%s" is out of bounds.`, path, index.Value, segment)
+			}
+			selected[i] = true
+		} else {
+			i := indexOfName(slice, index.Value.(string))
+			if i == -1 {
+				return nil, fmt.Errorf(`Unable to resolve path "%s": Named index "%s" in segment "%s" not found.`, path, index.Value, segment)
+			}
+			selected[i] = true
+		}
+	}
+	var elements []Target
+	for i := 0; i < slice.Len(); i++ {
+		if selected[i] {
+			elements = append(elements, element(parent, segment, slice, i))
+		}
+	}
+	return elements, nil
+}
+
+// Limits the index to [0, length].
+func limit(index int, length int) int {
+	if index < 0 {
+		return 0
+	} else if index > length {
+		return length
+	}
+	return index
+}
+
+// Turns a negative index which counts from the end into an index counting from the start.
+func absolute(index int, length int) int {
+	if index < 0 {
+		return index + length
+	}
+	return index
+}
+
+// Returns the element at the given index as target with a canonical path.
+func element(parent Target, segment Segment, slice reflect.Value, index int) Target {
+	canonical := Segment{segment.Name, IndexSegment, Index{NumericIndex, index}, Range{Undefined, Undefined, Undefined, nil}}
+	if name, ok := nameOf(slice.Index(index)); ok && name != "" && unique(slice, name) {
+		canonical.Index = Index{AlphaNumericIndex, name}
+	}
+	return Target{parent.Path.Append(Path{canonical}), slice.Index(index), slice, index}
+}
+
+// Turns a glob into a regular expression which matches whole names.
+func globExpression(glob string) *regexp.Regexp {
+	expression := regexp.QuoteMeta(glob)
+	expression = strings.Replace(expression, `\*`, ".*", -1)
+	expression = strings.Replace(expression, `\?`, ".", -1)
+	return regexp.MustCompile("^" + expression + "$")
+}
+
+// ------------------------------------------------------ reflection helpers
+
+// Returns the field of the given struct value whose json tag matches the specified name.
+func fieldByTag(context reflect.Value, name string) (reflect.Value, bool) {
+	contextType := context.Type()
+	for i := 0; i < contextType.NumField(); i++ {
+		tag := strings.Split(contextType.Field(i).Tag.Get("json"), ",")[0]
+		if tag == name {
+			return context.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Returns the index of the first element in the slice with the given name or -1 if there's no such element.
+func indexOfName(slice reflect.Value, name string) int {
+	for i := 0; i < slice.Len(); i++ {
+		if elementName, ok := nameOf(slice.Index(i)); ok && elementName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the value of the field "Name" if the given value is a struct having such a field.
+func nameOf(element reflect.Value) (string, bool) {
+	if element.Kind() != reflect.Struct {
+		return "", false
+	}
+	name := element.FieldByName("Name")
+	if !name.IsValid() || name.Kind() != reflect.String {
+		return "", false
+	}
+	return name.String(), true
+}
+
+// Returns true if there's exactly one element in the slice with the given name.
+func unique(slice reflect.Value, name string) bool {
+	var count int
+	for i := 0; i < slice.Len(); i++ {
+		if elementName, ok := nameOf(slice.Index(i)); ok && elementName == name {
+			count++
+		}
+	}
+	return count == 1
+}
@@ -0,0 +1,120 @@
+package path
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"reflect"
+	"strconv"
+)
+
+// Sets the attribute or object the path points to. The value is converted to the type of the
+// related field: Strings are taken as is (optionally enclosed in double quotes), numbers and
+// booleans are parsed and pointers, structs and slices are read as JSON. A pointer can be reset
+// using "null".
+func (path Path) Set(project *model.Project, value string) error {
+	if path.IsEmpty() {
+		return errors.New(`Unable to set path "": The project itself cannot be replaced.`)
+	}
+	target, err := path.resolveValue(project)
+	if err != nil {
+		return err
+	}
+	converted, err := convertFor(path, target, value)
+	if err != nil {
+		return err
+	}
+	target.Set(converted)
+	return nil
+}
+
+// Sets each target to the value with the same index. All values are converted before the first
+// target is modified. Thus either all or none of the targets are changed.
+func SetAll(targets []Target, values []string) error {
+	if len(targets) != len(values) {
+		return fmt.Errorf("Unable to set %d targets using %d values.", len(targets), len(values))
+	}
+	converted := make([]reflect.Value, len(targets))
+	for i, target := range targets {
+		if target.Path.IsEmpty() {
+			return errors.New(`Unable to set path "": The project itself cannot be replaced.`)
+		}
+		value, err := convertFor(target.Path, target.Value, values[i])
+		if err != nil {
+			return err
+		}
+		converted[i] = value
+	}
+	for i, target := range targets {
+		target.Value.Set(converted[i])
+	}
+	return nil
+}
+
+// Converts the value for the given target. Errors name the path and its last segment.
+func convertFor(path Path, target reflect.Value, value string) (reflect.Value, error) {
+	if !target.CanSet() {
+		return reflect.Value{}, fmt.Errorf(`Unable to set path "%s": Segment "%s" cannot be modified.`, path, path[len(path)-1])
+	}
+	converted, err := convert(target.Type(), value)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf(`Unable to set path "%s": Segment "%s" %s.`, path, path[len(path)-1], err)
+	}
+	return converted, nil
+}
+
+// Converts the string value to the specified type. The error message is meant to be appended
+// to the name of the segment which failed.
+func convert(typ reflect.Type, value string) (reflect.Value, error) {
+	switch typ.Kind() {
+	case reflect.String:
+		if len(value) > 1 && value[0] == '"' {
+			var str string
+			if err := json.Unmarshal([]byte(value), &str); err != nil {
+				return reflect.Value{}, fmt.Errorf(`expects a string, got %s`, value)
+			}
+			return reflect.ValueOf(str).Convert(typ), nil
+		}
+		return reflect.ValueOf(value).Convert(typ), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := strconv.ParseInt(value, 10, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`expects an integer, got "%s"`, value)
+		}
+		return reflect.ValueOf(number).Convert(typ), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := strconv.ParseUint(value, 10, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`expects a positive integer, got "%s"`, value)
+		}
+		return reflect.ValueOf(number).Convert(typ), nil
+
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`expects a boolean, got "%s"`, value)
+		}
+		return reflect.ValueOf(flag).Convert(typ), nil
+
+	case reflect.Ptr:
+		if value == "null" {
+			return reflect.Zero(typ), nil
+		}
+		pointer := reflect.New(typ.Elem())
+		if err := json.Unmarshal([]byte(value), pointer.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf(`expects a JSON encoded object, got "%s": %s`, value, err)
+		}
+		return pointer, nil
+
+	case reflect.Struct, reflect.Slice, reflect.Map:
+		pointer := reflect.New(typ)
+		if err := json.Unmarshal([]byte(value), pointer.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf(`expects a JSON encoded %s, got "%s": %s`, typ.Kind(), value, err)
+		}
+		return pointer.Elem(), nil
+	}
+	return reflect.Value{}, fmt.Errorf("has an unsupported type %s", typ)
+}
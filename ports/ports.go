@@ -0,0 +1,208 @@
+package ports
+
+import (
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/template"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The socket bindings which are published by the containers and listed by default.
+var WellKnown = []string{"http", "https", "remoting", ManagementHttp, ManagementNative}
+
+// The port offset of new servers is incremented by this value. Suggested offsets are multiples
+// of this value, too.
+const OffsetStep = 50
+
+// The management interfaces of the host controller as defined in the host templates.
+const (
+	ManagementHttp   = "management-http"
+	ManagementNative = "management-native"
+)
+
+var hostControllerPorts = []template.SocketBinding{
+	{Name: ManagementHttp, Port: "${jboss.management.http.port:9990}"},
+	{Name: ManagementNative, Port: "${jboss.management.native.port:9999}"},
+}
+
+// An effective port on a host. The port belongs either to a server or - if Server is empty -
+// to the host controller.
+type Port struct {
+	Host    string
+	Server  string
+	Binding string
+	Port    int
+}
+
+func (port Port) IsWellKnown() bool {
+	for _, name := range WellKnown {
+		if port.Binding == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns "<host>/<server>" or just "<host>" for the host controller.
+func (port Port) Owner() string {
+	if port.Server == "" {
+		return port.Host
+	}
+	return port.Host + "/" + port.Server
+}
+
+// Two ports of different owners on the same host using the same port number.
+type Conflict struct {
+	First  Port
+	Second Port
+}
+
+func (conflict Conflict) String() string {
+	return fmt.Sprintf("%s (%s) and %s (%s) both use port %d", conflict.First.Owner(), conflict.First.Binding,
+		conflict.Second.Owner(), conflict.Second.Binding, conflict.First.Port)
+}
+
+// Calculates the effective ports of all host controllers and servers. The ports of a server
+// are the ports of the socket binding group of its server group plus the port offset of the
+// server. Fixed ports and ports which are zero are not offset. Servers which reference unknown
+// server groups or socket binding groups are skipped.
+func Plan(project *model.Project, domain *template.Domain) ([]Port, error) {
+	var ports []Port
+	for _, host := range project.Hosts {
+		hostPorts, err := bindings(host.Name, "", hostControllerPorts, 0)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, hostPorts...)
+
+		for _, server := range host.Servers {
+			group, ok := SocketBindingGroup(project, domain, server)
+			if !ok {
+				continue
+			}
+			serverPorts, err := bindings(host.Name, server.Name, group.SocketBindings, server.PortOffset)
+			if err != nil {
+				return nil, err
+			}
+			ports = append(ports, serverPorts...)
+		}
+	}
+	return ports, nil
+}
+
+// Returns the socket binding group used by the server.
+func SocketBindingGroup(project *model.Project, domain *template.Domain, server model.Server) (template.SocketBindingGroup, bool) {
+	for _, serverGroup := range project.ServerGroups {
+		if serverGroup.Name == server.ServerGroup {
+			return domain.SocketBindingGroup(serverGroup.SocketBinding)
+		}
+	}
+	return template.SocketBindingGroup{}, false
+}
+
+func bindings(host, server string, socketBindings []template.SocketBinding, offset int) ([]Port, error) {
+	var ports []Port
+	for _, binding := range socketBindings {
+		port, err := DefaultPort(binding.Port)
+		if err != nil {
+			return nil, fmt.Errorf(`Invalid port "%s" of socket binding "%s": %s`, binding.Port, binding.Name, err)
+		}
+		if port == 0 {
+			continue
+		}
+		if !binding.FixedPort {
+			port += offset
+		}
+		ports = append(ports, Port{Host: host, Server: server, Binding: binding.Name, Port: port})
+	}
+	return ports, nil
+}
+
+// Returns the port of expressions like "8080" or "${jboss.http.port:8080}". Expressions
+// without a default value resolve to zero.
+func DefaultPort(expression string) (int, error) {
+	value := strings.TrimSpace(expression)
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")
+		index := strings.LastIndex(value, ":")
+		if index == -1 {
+			return 0, nil
+		}
+		value = value[index+1:]
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// Returns the smallest port offset which is a multiple of step and for which none of the
+// server's ports conflicts with another port on the same host. Returns -1 if there's no such
+// offset below the highest possible port.
+func FreeOffset(project *model.Project, domain *template.Domain, host string, server model.Server, plan []Port, step int) int {
+	group, ok := SocketBindingGroup(project, domain, server)
+	if !ok {
+		return -1
+	}
+	owner := Port{Host: host, Server: server.Name}.Owner()
+	used := make(map[int]bool)
+	for _, port := range plan {
+		if port.Host == host && port.Owner() != owner {
+			used[port.Port] = true
+		}
+	}
+
+	for offset := 0; offset <= maxPort; offset += step {
+		candidates, err := bindings(host, server.Name, group.SocketBindings, offset)
+		if err != nil {
+			return -1
+		}
+		free := true
+		for _, candidate := range candidates {
+			if candidate.Port > maxPort {
+				return -1
+			}
+			if used[candidate.Port] {
+				free = false
+				break
+			}
+		}
+		if free {
+			return offset
+		}
+	}
+	return -1
+}
+
+const maxPort = 65535
+
+// Returns all pairs of ports which use the same port number on the same host. Ports of the
+// same owner don't conflict with each other. The conflicts are sorted by host and port.
+func Conflicts(ports []Port) []Conflict {
+	var conflicts []Conflict
+	for i, first := range ports {
+		for _, second := range ports[i+1:] {
+			if first.Host == second.Host && first.Port == second.Port && first.Owner() != second.Owner() {
+				conflicts = append(conflicts, Conflict{first, second})
+			}
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].First.Host != conflicts[j].First.Host {
+			return hostIndex(ports, conflicts[i].First.Host) < hostIndex(ports, conflicts[j].First.Host)
+		}
+		return conflicts[i].First.Port < conflicts[j].First.Port
+	})
+	return conflicts
+}
+
+func hostIndex(ports []Port, host string) int {
+	for i, port := range ports {
+		if port.Host == host {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,134 @@
+package ports
+
+import (
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/template"
+	. "gopkg.in/check.v1"
+)
+
+// ------------------------------------------------------ setup
+
+type PlanSuite struct {
+	project *model.Project
+	domain  *template.Domain
+}
+
+func (s *PlanSuite) SetUpTest(c *C) {
+	s.domain = &template.Domain{
+		SocketBindingGroups: []template.SocketBindingGroup{
+			template.SocketBindingGroup{Name: "standard-sockets", SocketBindings: []template.SocketBinding{
+				{Name: "http", Port: "${jboss.http.port:8080}"},
+				{Name: "https", Port: "${jboss.https.port:8443}"},
+				{Name: "txn-recovery-environment", Port: "4712"},
+				{Name: "jgroups-mping", Port: "0"},
+				{Name: "jmx", Port: "1090", FixedPort: true},
+			}},
+		},
+	}
+	s.project = &model.Project{
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "main", SocketBinding: "standard-sockets"},
+			model.ServerGroup{Name: "broken", SocketBinding: "unknown-sockets"},
+		},
+		Hosts: []model.Host{
+			model.Host{Name: "master", DC: true},
+			model.Host{Name: "slave", Servers: []model.Server{
+				model.Server{Name: "server0", ServerGroup: "main"},
+				model.Server{Name: "server1", ServerGroup: "main", PortOffset: 100},
+				model.Server{Name: "server2", ServerGroup: "broken"},
+			}},
+		},
+	}
+}
+
+var _ = Suite(&PlanSuite{})
+
+// ------------------------------------------------------ plan tests
+
+func (s *PlanSuite) TestPlan(c *C) {
+	plan, err := Plan(s.project, s.domain)
+	c.Assert(err, IsNil)
+	c.Assert(plan, DeepEquals, []Port{
+		{"master", "", ManagementHttp, 9990},
+		{"master", "", ManagementNative, 9999},
+		{"slave", "", ManagementHttp, 9990},
+		{"slave", "", ManagementNative, 9999},
+		{"slave", "server0", "http", 8080},
+		{"slave", "server0", "https", 8443},
+		{"slave", "server0", "txn-recovery-environment", 4712},
+		{"slave", "server0", "jmx", 1090},
+		{"slave", "server1", "http", 8180},
+		{"slave", "server1", "https", 8543},
+		{"slave", "server1", "txn-recovery-environment", 4812},
+		{"slave", "server1", "jmx", 1090},
+	})
+}
+
+func (s *PlanSuite) TestConflicts(c *C) {
+	plan, err := Plan(s.project, s.domain)
+	c.Assert(err, IsNil)
+	conflicts := Conflicts(plan)
+	c.Assert(conflicts, HasLen, 1)
+	c.Assert(conflicts[0].String(), Equals, "slave/server0 (jmx) and slave/server1 (jmx) both use port 1090")
+
+	s.project.Hosts[1].Servers[1].PortOffset = 1910 // http 8080 + 1910 = 9990
+	plan, err = Plan(s.project, s.domain)
+	c.Assert(err, IsNil)
+	var descriptions []string
+	for _, conflict := range Conflicts(plan) {
+		descriptions = append(descriptions, conflict.String())
+	}
+	c.Assert(descriptions, DeepEquals, []string{
+		"slave/server0 (jmx) and slave/server1 (jmx) both use port 1090",
+		"slave (management-http) and slave/server1 (http) both use port 9990",
+	})
+}
+
+func (s *PlanSuite) TestNoConflictsAcrossHosts(c *C) {
+	s.project.Hosts[0].Servers = []model.Server{model.Server{Name: "server0", ServerGroup: "main"}}
+	s.project.Hosts[1].Servers = s.project.Hosts[1].Servers[:1]
+	plan, err := Plan(s.project, s.domain)
+	c.Assert(err, IsNil)
+	c.Assert(Conflicts(plan), HasLen, 0)
+}
+
+func (s *PlanSuite) TestFreeOffset(c *C) {
+	// without the fixed jmx port
+	s.domain.SocketBindingGroups[0].SocketBindings = s.domain.SocketBindingGroups[0].SocketBindings[:4]
+	plan, err := Plan(s.project, s.domain)
+	c.Assert(err, IsNil)
+	server := model.Server{Name: "server3", ServerGroup: "main"}
+	// server0 and server1 use offset 0 and 100
+	c.Assert(FreeOffset(s.project, s.domain, "slave", server, plan, OffsetStep), Equals, 50)
+	c.Assert(FreeOffset(s.project, s.domain, "slave", server, plan, 100), Equals, 200)
+	c.Assert(FreeOffset(s.project, s.domain, "master", server, plan, OffsetStep), Equals, 0)
+}
+
+func (s *PlanSuite) TestFreeOffsetFixedPort(c *C) {
+	plan, err := Plan(s.project, s.domain)
+	c.Assert(err, IsNil)
+	c.Assert(FreeOffset(s.project, s.domain, "slave", s.project.Hosts[1].Servers[1], plan, OffsetStep), Equals, -1)
+}
+
+func (s *PlanSuite) TestDefaultPort(c *C) {
+	for expression, expected := range map[string]int{
+		"8080":                     8080,
+		"${jboss.http.port:8080}":  8080,
+		"${jboss.a,jboss.b:8443}":  8443,
+		"${jboss.http.port}":       0,
+		"":                         0,
+		" ${jboss.ajp.port:8009} ": 8009,
+	} {
+		port, err := DefaultPort(expression)
+		c.Assert(err, IsNil)
+		c.Assert(port, Equals, expected, Commentf("expression: %q", expression))
+	}
+}
+
+// ------------------------------------------------------ error tests
+
+func (s *PlanSuite) TestInvalidPort(c *C) {
+	s.domain.SocketBindingGroups[0].SocketBindings[0].Port = "${jboss.http.port:http}"
+	_, err := Plan(s.project, s.domain)
+	c.Assert(err, ErrorMatches, `Invalid port "\$\{jboss.http.port:http\}" of socket binding "http": .*`)
+}
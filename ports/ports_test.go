@@ -0,0 +1,9 @@
+package ports
+
+import (
+	. "gopkg.in/check.v1"
+	"testing"
+)
+
+// triggers all tests in this package
+func TestPorts(t *testing.T) { TestingT(t) }
@@ -58,7 +58,10 @@ func Start(info string, project *model.Project) {
 		}
 
 		AddHistory(cmdline)
-		tokens := strings.Fields(cmdline)
+		tokens := command.Tokens(cmdline)
+		if len(tokens) == 0 {
+			continue
+		}
 		cmd, valid := command.Registry[tokens[0]]
 		if !valid {
 			fmt.Printf("\nUnknown command: \"%s\"\n", tokens[0])
@@ -0,0 +1,59 @@
+package template
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+)
+
+// The parts of a domain template which are referenced by the project model.
+type Domain struct {
+	Profiles            []Profile            `xml:"profiles>profile"`
+	SocketBindingGroups []SocketBindingGroup `xml:"socket-binding-groups>socket-binding-group"`
+}
+
+type Profile struct {
+	Name string `xml:"name,attr"`
+}
+
+type SocketBindingGroup struct {
+	Name           string          `xml:"name,attr"`
+	SocketBindings []SocketBinding `xml:"socket-binding"`
+}
+
+type SocketBinding struct {
+	Name      string `xml:"name,attr"`
+	Port      string `xml:"port,attr"`
+	FixedPort bool   `xml:"fixed-port,attr"`
+}
+
+// Reads the profiles and socket binding groups of the given domain template.
+func ReadDomain(filename string) (*Domain, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf(`Unable to read domain template "%s": %s`, filename, err)
+	}
+	var domain Domain
+	if err := xml.Unmarshal(data, &domain); err != nil {
+		return nil, fmt.Errorf(`Unable to parse domain template "%s": %s`, filename, err)
+	}
+	return &domain, nil
+}
+
+func (domain *Domain) HasProfile(name string) bool {
+	for _, profile := range domain.Profiles {
+		if profile.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (domain *Domain) SocketBindingGroup(name string) (SocketBindingGroup, bool) {
+	for _, group := range domain.SocketBindingGroups {
+		if group.Name == name {
+			return group, true
+		}
+	}
+	return SocketBindingGroup{}, false
+}
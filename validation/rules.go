@@ -0,0 +1,310 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	"github.com/hpehl/whatunga/ports"
+	"os"
+)
+
+func init() {
+	Register(duplicateNames)
+	Register(unknownServerGroups)
+	Register(templateReferences)
+	Register(domainController)
+	Register(deploymentPaths)
+	Register(portConflicts)
+	Register(unusedServerGroups)
+	Register(emptyHosts)
+}
+
+var duplicateNames = Rule{
+	"duplicate-names",
+	"Server groups, hosts, users and the servers of a host and the deployments of a server group must have unique names.",
+	func(context *Context) []Finding {
+		var findings []Finding
+		project := context.Project
+
+		var names []string
+		for _, serverGroup := range project.ServerGroups {
+			names = append(names, serverGroup.Name)
+		}
+		findings = append(findings, duplicates(context, "server group", "server-groups[%d]", names)...)
+
+		names = nil
+		for _, host := range project.Hosts {
+			names = append(names, host.Name)
+		}
+		findings = append(findings, duplicates(context, "host", "hosts[%d]", names)...)
+
+		for i, host := range project.Hosts {
+			names = nil
+			for _, server := range host.Servers {
+				names = append(names, server.Name)
+			}
+			findings = append(findings, duplicates(context, "server", fmt.Sprintf("hosts[%d].servers[%%d]", i), names)...)
+		}
+
+		for i, serverGroup := range project.ServerGroups {
+			names = nil
+			for _, deployment := range serverGroup.Deployments {
+				names = append(names, deployment.Name)
+			}
+			findings = append(findings, duplicates(context, "deployment", fmt.Sprintf("server-groups[%d].deployments[%%d]", i), names)...)
+		}
+
+		names = []string{project.Config.ConsoleUser.Name, project.Config.DomainUser.Name}
+		for _, user := range project.Users {
+			names = append(names, user.Name)
+		}
+		for i, name := range names[2:] {
+			if name == names[0] || name == names[1] {
+				findings = append(findings, Finding{Severity: Error, Path: canonical(project, fmt.Sprintf("users[%d]", i)),
+					Message: fmt.Sprintf(`User "%s" has the same name as the console or domain user.`, name)})
+			}
+		}
+		findings = append(findings, duplicates(context, "user", "users[%d]", names[2:])...)
+		return findings
+	},
+}
+
+var unknownServerGroups = Rule{
+	"unknown-server-groups",
+	"Servers must reference an existing server group.",
+	func(context *Context) []Finding {
+		var findings []Finding
+		project := context.Project
+
+		var serverGroups []string
+		for _, serverGroup := range project.ServerGroups {
+			serverGroups = append(serverGroups, serverGroup.Name)
+		}
+		for i, host := range project.Hosts {
+			for j, server := range host.Servers {
+				p := canonical(project, fmt.Sprintf("hosts[%d].servers[%d].server-group", i, j))
+				if server.ServerGroup == "" {
+					findings = append(findings, Finding{Severity: Error, Path: p,
+						Message: fmt.Sprintf(`Server "%s" is not assigned to a server group.`, server.Name)})
+				} else if !contains(serverGroups, server.ServerGroup) {
+					findings = append(findings, Finding{Severity: Error, Path: p,
+						Message: fmt.Sprintf(`Server "%s" references the unknown server group "%s".`, server.Name, server.ServerGroup)})
+				}
+			}
+		}
+		return findings
+	},
+}
+
+var templateReferences = Rule{
+	"template-references",
+	"Profiles and socket binding groups of server groups must be defined in the domain template.",
+	func(context *Context) []Finding {
+		if context.Domain == nil {
+			return nil // already reported
+		}
+		var findings []Finding
+		project := context.Project
+
+		for i, serverGroup := range project.ServerGroups {
+			if !context.Domain.HasProfile(serverGroup.Profile) {
+				findings = append(findings, Finding{Severity: Error,
+					Path: canonical(project, fmt.Sprintf("server-groups[%d].profile", i)),
+					Message: fmt.Sprintf(`Profile "%s" of server group "%s" is not defined in the domain template "%s".`,
+						serverGroup.Profile, serverGroup.Name, project.Config.Templates.Domain)})
+			}
+			if _, found := context.Domain.SocketBindingGroup(serverGroup.SocketBinding); !found {
+				findings = append(findings, Finding{Severity: Error,
+					Path: canonical(project, fmt.Sprintf("server-groups[%d].socket-binding", i)),
+					Message: fmt.Sprintf(`Socket binding group "%s" of server group "%s" is not defined in the domain template "%s".`,
+						serverGroup.SocketBinding, serverGroup.Name, project.Config.Templates.Domain)})
+			}
+		}
+		return findings
+	},
+}
+
+var domainController = Rule{
+	"domain-controller",
+	"Exactly one host must be the domain controller.",
+	func(context *Context) []Finding {
+		var findings []Finding
+		project := context.Project
+
+		var dcs []int
+		for i, host := range project.Hosts {
+			if host.DC {
+				dcs = append(dcs, i)
+			}
+		}
+		if len(dcs) == 0 {
+			findings = append(findings, Finding{Severity: Error, Path: canonical(project, "hosts"),
+				Message: "No host is marked as domain controller."})
+		}
+		if len(dcs) > 1 {
+			for _, i := range dcs {
+				findings = append(findings, Finding{Severity: Error,
+					Path:    canonical(project, fmt.Sprintf("hosts[%d].domain-controller", i)),
+					Message: fmt.Sprintf(`Host "%s" is one of %d hosts marked as domain controller.`, project.Hosts[i].Name, len(dcs))})
+			}
+		}
+		return findings
+	},
+}
+
+var deploymentPaths = Rule{
+	"deployment-paths",
+	"Deployment artifacts must exist.",
+	func(context *Context) []Finding {
+		var findings []Finding
+		project := context.Project
+
+		for i, serverGroup := range project.ServerGroups {
+			for j, deployment := range serverGroup.Deployments {
+				p := canonical(project, fmt.Sprintf("server-groups[%d].deployments[%d].path", i, j))
+				if deployment.Path == "" {
+					findings = append(findings, Finding{Severity: Error, Path: p,
+						Message: fmt.Sprintf(`Deployment "%s" has no path.`, deployment.Name)})
+				} else if _, err := os.Stat(deployment.Path); err != nil {
+					findings = append(findings, Finding{Severity: Error, Path: p,
+						Message: fmt.Sprintf(`Artifact "%s" of deployment "%s" does not exist.`, deployment.Path, deployment.Name)})
+				}
+			}
+		}
+		return findings
+	},
+}
+
+var portConflicts = Rule{
+	"port-conflicts",
+	"The effective ports of the servers and the host controller of a host must not overlap.",
+	func(context *Context) []Finding {
+		if context.Domain == nil {
+			return nil // already reported
+		}
+		var findings []Finding
+		project := context.Project
+
+		plan, err := ports.Plan(project, context.Domain)
+		if err != nil {
+			return []Finding{Finding{Severity: Error, Path: canonical(project, "config.templates.domain"), Message: err.Error()}}
+		}
+		for _, conflict := range ports.Conflicts(plan) {
+			// the host controller comes first in the plan, so the second port always belongs to a server
+			first, second := portPath(project, conflict.First), portPath(project, conflict.Second)
+			message := fmt.Sprintf(`Socket binding "%s" of %s and socket binding "%s" of %s both use port %d.`,
+				conflict.First.Binding, first, conflict.Second.Binding, second, conflict.First.Port)
+			if host, server, ok := findServer(project, conflict.Second); ok {
+				offset := ports.FreeOffset(project, context.Domain, host.Name, server, plan, ports.OffsetStep)
+				if offset == -1 {
+					message += " The conflict cannot be resolved by a port offset."
+				} else {
+					message += fmt.Sprintf(" Use port offset %d for server \"%s\" to resolve the conflict.", offset, server.Name)
+				}
+			}
+			findings = append(findings, Finding{Severity: Error, Path: second, Message: message})
+		}
+		return findings
+	},
+}
+
+var unusedServerGroups = Rule{
+	"unused-server-groups",
+	"Server groups should be referenced by at least one server.",
+	func(context *Context) []Finding {
+		var findings []Finding
+		project := context.Project
+
+		var used []string
+		for _, host := range project.Hosts {
+			for _, server := range host.Servers {
+				used = append(used, server.ServerGroup)
+			}
+		}
+		for i, serverGroup := range project.ServerGroups {
+			if !contains(used, serverGroup.Name) {
+				findings = append(findings, Finding{Severity: Info,
+					Path:    canonical(project, fmt.Sprintf("server-groups[%d]", i)),
+					Message: fmt.Sprintf(`Server group "%s" has no servers.`, serverGroup.Name)})
+			}
+		}
+		return findings
+	},
+}
+
+var emptyHosts = Rule{
+	"empty-hosts",
+	"Hosts which are not the domain controller should have servers.",
+	func(context *Context) []Finding {
+		var findings []Finding
+		project := context.Project
+
+		for i, host := range project.Hosts {
+			if !host.DC && len(host.Servers) == 0 {
+				findings = append(findings, Finding{Severity: Warning,
+					Path:    canonical(project, fmt.Sprintf("hosts[%d]", i)),
+					Message: fmt.Sprintf(`Host "%s" is no domain controller and has no servers.`, host.Name)})
+			}
+		}
+		return findings
+	},
+}
+
+// ------------------------------------------------------ helper functions
+
+// Reports each name which occurs more than once. The format is used to build the path of an
+// object and must contain one %d verb for the index.
+func duplicates(context *Context, kind string, format string, names []string) []Finding {
+	var findings []Finding
+	var occurrences = make(map[string]int)
+	for _, name := range names {
+		occurrences[name]++
+	}
+	for i, name := range names {
+		if occurrences[name] > 1 {
+			findings = append(findings, Finding{Severity: Error, Path: canonical(context.Project, fmt.Sprintf(format, i)),
+				Message: fmt.Sprintf(`The %s name "%s" is used %d times.`, kind, name, occurrences[name])})
+		}
+	}
+	return findings
+}
+
+// Returns the canonical path of the server or host controller which owns the port.
+func portPath(project *model.Project, port ports.Port) path.Path {
+	for i, host := range project.Hosts {
+		if host.Name != port.Host {
+			continue
+		}
+		if port.Server == "" {
+			return canonical(project, fmt.Sprintf("hosts[%d]", i))
+		}
+		for j, server := range host.Servers {
+			if server.Name == port.Server {
+				return canonical(project, fmt.Sprintf("hosts[%d].servers[%d]", i, j))
+			}
+		}
+	}
+	return nil
+}
+
+func findServer(project *model.Project, port ports.Port) (model.Host, model.Server, bool) {
+	for _, host := range project.Hosts {
+		if host.Name == port.Host {
+			for _, server := range host.Servers {
+				if server.Name == port.Server {
+					return host, server, true
+				}
+			}
+		}
+	}
+	return model.Host{}, model.Server{}, false
+}
+
+func contains(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/path"
+	"github.com/hpehl/whatunga/template"
+)
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+type Severity int
+
+// A finding reported by a rule.
+type Finding struct {
+	Severity Severity
+	// The object or attribute which caused the finding
+	Path    path.Path
+	Rule    string
+	Message string
+}
+
+// A validation rule. Rules are registered using Register() and executed in the order of
+// their registration.
+type Rule struct {
+	// The name of the rule
+	Name string
+	// A short description of the rule
+	Description string
+	// The function which checks the project model
+	Check func(context *Context) []Finding
+}
+
+// The input of a validation run.
+type Context struct {
+	Project *model.Project
+	// The parsed domain template or nil if the domain template cannot be read
+	Domain *template.Domain
+}
+
+var rules []Rule
+
+// Adds a rule to the list of rules executed by Validate().
+func Register(rule Rule) {
+	rules = append(rules, rule)
+}
+
+func Rules() []Rule {
+	return rules
+}
+
+// Runs all registered rules against the project and returns their findings.
+func Validate(project *model.Project) []Finding {
+	var findings []Finding
+	context := &Context{Project: project}
+
+	domain, err := template.ReadDomain(project.Config.Templates.Domain)
+	if err != nil {
+		findings = append(findings, Finding{Error, canonical(project, "config.templates.domain"), "templates", err.Error()})
+	} else {
+		context.Domain = domain
+	}
+	for _, rule := range rules {
+		for _, finding := range rule.Check(context) {
+			finding.Rule = rule.Name
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// Counts the findings with the given severity.
+func Count(findings []Finding, severity Severity) int {
+	var count int
+	for _, finding := range findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns the findings as JSON document which can be processed by other tools.
+func Report(findings []Finding) ([]byte, error) {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	return json.MarshalIndent(struct {
+		Valid    bool      `json:"valid"`
+		Findings []Finding `json:"findings"`
+	}{Count(findings, Error) == 0, findings}, "", "  ")
+}
+
+// ------------------------------------------------------ severity & finding methods
+
+func (severity Severity) String() string {
+	switch severity {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Info:
+		return "info"
+	}
+	return fmt.Sprintf("severity(%d)", int(severity))
+}
+
+func (severity Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(severity.String())
+}
+
+func (finding Finding) String() string {
+	return fmt.Sprintf("%-8s %s: %s", finding.Severity, finding.Path, finding.Message)
+}
+
+func (finding Finding) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Severity Severity `json:"severity"`
+		Path     string   `json:"path"`
+		Rule     string   `json:"rule"`
+		Message  string   `json:"message"`
+	}{finding.Severity, finding.Path.String(), finding.Rule, finding.Message})
+}
+
+// ------------------------------------------------------ helper functions
+
+// Parses the path and returns its canonical form.
+func canonical(project *model.Project, p string) path.Path {
+	pth, err := path.Parse(p)
+	if err != nil {
+		return nil
+	}
+	targets, err := pth.ResolveAll(project)
+	if err != nil || len(targets) != 1 {
+		return pth
+	}
+	return targets[0].Path
+}
@@ -0,0 +1,150 @@
+package validation
+
+import (
+	"encoding/json"
+	"github.com/hpehl/whatunga/model"
+	"github.com/hpehl/whatunga/template"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// ------------------------------------------------------ setup
+
+type ValidationRulesSuite struct {
+	project *model.Project
+}
+
+func (s *ValidationRulesSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	domain := filepath.Join(dir, "domain.xml")
+	data, err := template.Asset("templates/wildfly/8.1/domain.xml")
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(domain, data, model.FilePerm), IsNil)
+	artifact := filepath.Join(dir, "app.war")
+	c.Assert(ioutil.WriteFile(artifact, []byte("app"), model.FilePerm), IsNil)
+
+	s.project = &model.Project{
+		Name:    "test",
+		Version: "1.0",
+		Config: model.Config{
+			Templates:   model.Templates{Domain: domain},
+			ConsoleUser: model.User{Name: "admin"},
+			DomainUser:  model.User{Name: "dc"},
+		},
+		ServerGroups: []model.ServerGroup{
+			model.ServerGroup{Name: "main", Profile: "full", SocketBinding: "full-sockets",
+				Deployments: []model.Deployment{model.Deployment{Name: "app-war", RuntimeName: "app.war", Path: artifact}}},
+		},
+		Hosts: []model.Host{
+			model.Host{Name: "master", DC: true},
+			model.Host{Name: "slave", Servers: []model.Server{
+				model.Server{Name: "server0", ServerGroup: "main"},
+			}},
+		},
+	}
+}
+
+var _ = Suite(&ValidationRulesSuite{})
+
+// ------------------------------------------------------ validation tests
+
+func (s *ValidationRulesSuite) TestValid(c *C) {
+	c.Assert(Validate(s.project), HasLen, 0)
+}
+
+func (s *ValidationRulesSuite) TestDuplicateNames(c *C) {
+	s.project.Hosts[1].Servers = append(s.project.Hosts[1].Servers, model.Server{Name: "server0", ServerGroup: "main"})
+	s.project.Users = []model.User{model.User{Name: "admin"}}
+
+	assertFindings(c, Validate(s.project),
+		`error    hosts[slave].servers[0]: The server name "server0" is used 2 times.`,
+		`error    hosts[slave].servers[1]: The server name "server0" is used 2 times.`,
+		`error    users[admin]: User "admin" has the same name as the console or domain user.`)
+}
+
+func (s *ValidationRulesSuite) TestUnknownServerGroup(c *C) {
+	s.project.Hosts[1].Servers[0].ServerGroup = "foo"
+
+	assertFindings(c, Validate(s.project),
+		`error    hosts[slave].servers[server0].server-group: Server "server0" references the unknown server group "foo".`,
+		`info     server-groups[main]: Server group "main" has no servers.`)
+}
+
+func (s *ValidationRulesSuite) TestTemplateReferences(c *C) {
+	s.project.ServerGroups[0].Profile = "foo"
+	s.project.ServerGroups[0].SocketBinding = "bar"
+	findings := Validate(s.project)
+
+	c.Assert(findings, HasLen, 2)
+	c.Assert(findings[0].Path.String(), Equals, "server-groups[main].profile")
+	c.Assert(findings[1].Path.String(), Equals, "server-groups[main].socket-binding")
+}
+
+func (s *ValidationRulesSuite) TestDomainController(c *C) {
+	s.project.Hosts[0].DC = false
+	assertFindings(c, Validate(s.project),
+		`error    hosts: No host is marked as domain controller.`,
+		`warning  hosts[master]: Host "master" is no domain controller and has no servers.`)
+
+	s.project.Hosts[0].DC = true
+	s.project.Hosts[1].DC = true
+	assertFindings(c, Validate(s.project),
+		`error    hosts[master].domain-controller: Host "master" is one of 2 hosts marked as domain controller.`,
+		`error    hosts[slave].domain-controller: Host "slave" is one of 2 hosts marked as domain controller.`)
+}
+
+func (s *ValidationRulesSuite) TestDeploymentPaths(c *C) {
+	s.project.ServerGroups[0].Deployments[0].Path = "/does/not/exist.war"
+	assertFindings(c, Validate(s.project),
+		`error    server-groups[main].deployments[app-war].path: Artifact "/does/not/exist.war" of deployment "app-war" does not exist.`)
+}
+
+func (s *ValidationRulesSuite) TestPortConflicts(c *C) {
+	s.project.Hosts[1].Servers = append(s.project.Hosts[1].Servers, model.Server{Name: "server1", ServerGroup: "main", PortOffset: 1})
+	assertFindings(c, Validate(s.project),
+		`error    hosts[slave].servers[server1]: Socket binding "jacorb-ssl" of hosts[slave].servers[server0] and socket binding "jacorb" of hosts[slave].servers[server1] both use port 3529. Use port offset 50 for server "server1" to resolve the conflict.`,
+		`error    hosts[slave].servers[server1]: Socket binding "txn-status-manager" of hosts[slave].servers[server0] and socket binding "txn-recovery-environment" of hosts[slave].servers[server1] both use port 4713. Use port offset 50 for server "server1" to resolve the conflict.`)
+}
+
+func (s *ValidationRulesSuite) TestPortConflictWithHostController(c *C) {
+	// 8080 + 1910 = 9990
+	s.project.Hosts[1].Servers[0].PortOffset = 1910
+	assertFindings(c, Validate(s.project),
+		`error    hosts[slave].servers[server0]: Socket binding "management-http" of hosts[slave] and socket binding "http" of hosts[slave].servers[server0] both use port 9990. Use port offset 0 for server "server0" to resolve the conflict.`)
+}
+
+func (s *ValidationRulesSuite) TestMissingTemplate(c *C) {
+	s.project.Config.Templates.Domain = "/does/not/exist.xml"
+	findings := Validate(s.project)
+
+	c.Assert(findings, HasLen, 1)
+	c.Assert(findings[0].Rule, Equals, "templates")
+	c.Assert(findings[0].Path.String(), Equals, "config.templates.domain")
+}
+
+func (s *ValidationRulesSuite) TestReport(c *C) {
+	s.project.Hosts[0].DC = false
+	data, err := Report(Validate(s.project)[0:1])
+	c.Assert(err, IsNil)
+
+	var report map[string]interface{}
+	c.Assert(json.Unmarshal(data, &report), IsNil)
+	c.Assert(report["valid"], Equals, false)
+	c.Assert(report["findings"], DeepEquals, []interface{}{map[string]interface{}{
+		"severity": "error",
+		"path":     "hosts",
+		"rule":     "domain-controller",
+		"message":  "No host is marked as domain controller.",
+	}})
+}
+
+// ------------------------------------------------------ helper functions
+
+func assertFindings(c *C, findings []Finding, expected ...string) {
+	var actual []string
+	for _, finding := range findings {
+		actual = append(actual, finding.String())
+	}
+	c.Assert(actual, DeepEquals, expected)
+}
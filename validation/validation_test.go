@@ -0,0 +1,9 @@
+package validation
+
+import (
+	. "gopkg.in/check.v1"
+	"testing"
+)
+
+// triggers all tests in this package
+func TestValidation(t *testing.T) { TestingT(t) }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/hpehl/whatunga/model"
 	"github.com/hpehl/whatunga/shell"
+	"github.com/hpehl/whatunga/validation"
 	"os"
 	"path"
 )
@@ -12,16 +13,18 @@ import (
 var targetFlag model.Target = model.SupportedTargets[1]
 var nameFlag string
 var versionFlag string
+var validateFlag bool
 
 func init() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [--target=target] [--name=name] [--version=version] <directory>\n\n", shell.AppName)
+		fmt.Fprintf(os.Stderr, "Usage: %s [--target=target] [--name=name] [--version=version] [--validate] <directory>\n\n", shell.AppName)
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 	flag.Var(&targetFlag, "target", fmt.Sprintf("Specifies the target. Valid targets: %v.", model.SupportedTargets))
 	flag.StringVar(&nameFlag, "name", "", "The name of the project. If you omit the name, the directories name is taken.")
 	flag.StringVar(&versionFlag, "version", "1.0", `The project version which is "1.0" by default.`)
+	flag.BoolVar(&validateFlag, "validate", false, "Validates an existing project, prints the findings as JSON and exits. The exit status is 1 if there are errors.")
 }
 
 func main() {
@@ -46,7 +49,17 @@ func main() {
 
 	var welcome string
 	var project *model.Project
-	if os.IsNotExist(err) {
+	if validateFlag {
+		if err != nil || !fileInfo.Mode().IsDir() {
+			wrongUsage(fmt.Sprintf("\"%s\" is not an existing project!", directory))
+		}
+		p, err := model.OpenProject(directory)
+		if err != nil {
+			wrongUsage(err.Error())
+		}
+		os.Exit(validateProject(p))
+
+	} else if os.IsNotExist(err) {
 		p, err := model.NewProject(directory, nameFlag, versionFlag, targetFlag)
 		if err != nil {
 			wrongUsage(err.Error())
@@ -70,6 +83,21 @@ func main() {
 	shell.Start(welcome, project)
 }
 
+// Prints the findings as JSON and returns the exit status.
+func validateProject(project *model.Project) int {
+	findings := validation.Validate(project)
+	data, err := validation.Report(findings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("%s\n", string(data))
+	if validation.Count(findings, validation.Error) != 0 {
+		return 1
+	}
+	return 0
+}
+
 func wrongUsage(why string) {
 	fmt.Fprintf(os.Stderr, "%s\n\n", why)
 	flag.Usage()